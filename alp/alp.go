@@ -4,6 +4,8 @@ import (
 	"encoding/binary"
 	"errors"
 	"math"
+	"slices"
+	"unsafe"
 
 	"github.com/parquet-go/bitpack"
 	"github.com/parquet-go/bitpack/unsafecast"
@@ -11,20 +13,30 @@ import (
 
 const (
 	// MaxExponent is the maximum exponent to try for encoding
-	MaxExponent = 10
+	MaxExponent = 18
 	// MinExponent is the minimum exponent to try
-	MinExponent = -10
+	MinExponent = 0
+	// MaxFactor is the maximum factor to try for encoding
+	MaxFactor = 18
 	// SamplingSize is the number of values to sample for finding optimal encoding
 	SamplingSize = 1024
 	// MetadataSize is the size of metadata in bytes.
-	MetadataSize = 23
+	MetadataSize = 29
+	// ExceptionSize is the size in bytes of a single exception: a uint32
+	// position followed by the raw bits of the float64 value.
+	ExceptionSize = 4 + 8
 )
 
 // Pre-computed powers of 10 for fast lookup
-var powersOf10 = [21]float64{
-	1e-10, 1e-9, 1e-8, 1e-7, 1e-6, 1e-5, 1e-4, 1e-3, 1e-2, 1e-1,
-	1e0,
-	1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10,
+var powersOf10 = [19]float64{
+	1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9,
+	1e10, 1e11, 1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18,
+}
+
+// Pre-computed inverse powers of 10 for fast lookup
+var inversePowersOf10 = [19]float64{
+	1e-0, 1e-1, 1e-2, 1e-3, 1e-4, 1e-5, 1e-6, 1e-7, 1e-8, 1e-9,
+	1e-10, 1e-11, 1e-12, 1e-13, 1e-14, 1e-15, 1e-16, 1e-17, 1e-18,
 }
 
 // EncodingType represents the type of encoding used
@@ -35,22 +47,54 @@ const (
 	EncodingALP          EncodingType = 1
 	EncodingConstant     EncodingType = 2
 	EncodingUncompressed EncodingType = 3
+	EncodingRD           EncodingType = 4
+)
+
+// ValueType is the type of the encoded values.
+type ValueType uint8
+
+const (
+	ValueTypeFloat64 ValueType = 0
+	ValueTypeFloat32 ValueType = 1
 )
 
+// Float is the set of floating point types which can be encoded with ALP.
+type Float interface {
+	float32 | float64
+}
+
 var ErrInvalidEncoding = errors.New("invalid encoding")
 
 // CompressionMetadata contains metadata about the compressed data
 type CompressionMetadata struct {
-	EncodingType  EncodingType
-	Count         int32
-	Exponent      int8
-	BitWidth      uint8
-	FrameOfRef    int64
+	EncodingType EncodingType
+	Count        int32
+	Exponent     int8
+	Factor       int8
+	BitWidth     uint8
+	FrameOfRef   int64
+	// ConstantValue is the value of constant blocks. For float32 blocks its
+	// low 32 bits hold the bits of the float32 value.
 	ConstantValue float64
+	// ExceptionCount is the number of values stored verbatim and patched in
+	// after decoding.
+	ExceptionCount int32
+	ValueType      ValueType
+	// Checksum is set if the data ends with a checksum trailer, see
+	// AppendChecksum.
+	Checksum bool
+	// Stream is set if the data was produced by a stream encoder.
+	Stream bool
 }
 
 // Encode compresses an array of float64 values using ALP
 func Encode(dst []byte, src []float64) []byte {
+	var e Encoder
+	return encode(&e, dst, src)
+}
+
+func encode[F Float](e *Encoder, dst []byte, src []F) []byte {
+	valueType := valueTypeOf[F]()
 	switch {
 	case len(src) == 0:
 		if cap(dst) < MetadataSize {
@@ -60,58 +104,70 @@ func Encode(dst []byte, src []float64) []byte {
 		encodeMetadata(dst, CompressionMetadata{
 			EncodingType: EncodingNone,
 			Count:        0,
+			ValueType:    valueType,
 		})
 		return dst
 	case isConstant(src):
 		if cap(dst) < MetadataSize {
 			dst = make([]byte, MetadataSize)
 		}
+		dst = dst[:MetadataSize]
 		encodeMetadata(dst, CompressionMetadata{
 			EncodingType:  EncodingConstant,
 			Count:         int32(len(src)),
-			ConstantValue: src[0],
+			ConstantValue: math.Float64frombits(floatBits(src[0])),
+			ValueType:     valueType,
 		})
 		return dst
 	}
 
-	// Find best exponent
-	exponent := findBestExponent(src)
-	factor := powersOf10[exponent+10]
-
-	// Convert to integers
-	forValues := encodeToIntegers(src, factor)
-
-	// Apply frame-of-reference encoding
-	minValue := forValues[0]
-	for _, v := range forValues {
-		minValue = min(minValue, v)
+	// Find best exponent and factor and fall back to ALP-RD or raw values
+	// when decimal scaling does not compress the data. ALP-RD is only used
+	// for float64 values.
+	scale, cost := findBestScaling(e, src)
+	uncompressedCost := min(len(src), SamplingSize) * valueSize[F]() * 8
+	if !isFloat32[F]() && cost > min(len(src), SamplingSize)*rdMinRightBitWidth {
+		values := unsafecast.Slice[float64](src)
+		split := e.findBestRDSplit(values)
+		if split.cost < min(cost, uncompressedCost) {
+			return e.encodeRD(dst, values, split)
+		}
 	}
-	for i, v := range forValues {
-		forValues[i] = v - minValue
+	if cost >= uncompressedCost {
+		return encodeUncompressed(dst, src)
 	}
+	// Convert to integers, collecting values which do not survive the round-trip.
+	forValues, exceptions := encodeToIntegers(e, src, scale)
 
-	// Find bit-width for signed integers.
-	bitWidth := 0
-	for _, v := range forValues {
-		bits := CalculateBitWidth(uint64(v))
-		bitWidth = max(bitWidth, bits)
-	}
+	// Apply frame-of-reference encoding, moving outliers to the exceptions.
+	minValue, maxValue := e.findBounds(forValues, exceptionSize[F]())
+	exceptions = e.applyFrameOfReference(forValues, exceptions, minValue, maxValue)
+	bitWidth := CalculateBitWidth(uint64(maxValue - minValue))
 
-	// Pack using signed integer packing.
+	// Pack using signed integer packing. Integers of float32 values fit into
+	// 32 bits, so they can be unpacked as int32.
+	exceptionsSize := len(exceptions) * exceptionSize[F]()
 	packedSize := bitpack.ByteCount(uint(len(forValues)*bitWidth)) + bitpack.PaddingInt64
-	if cap(dst) < packedSize+MetadataSize {
-		dst = make([]byte, packedSize+MetadataSize)
+	totalSize := MetadataSize + exceptionsSize + packedSize
+	if cap(dst) < totalSize {
+		dst = make([]byte, totalSize)
 	}
-	dst = dst[:packedSize]
-	bitpack.Pack(dst[MetadataSize:], forValues, uint(bitWidth))
+	dst = dst[:totalSize]
+	encodeExceptions(dst[MetadataSize:], src, exceptions)
+	bitpack.Pack(dst[MetadataSize+exceptionsSize:], forValues, uint(bitWidth))
+	// Pack does not write the padding, which may hold stale bytes of dst.
+	clear(dst[totalSize-bitpack.PaddingInt64:])
 
 	// Create metadata
 	metadata := CompressionMetadata{
-		EncodingType: EncodingALP,
-		Count:        int32(len(src)),
-		Exponent:     int8(exponent),
-		BitWidth:     uint8(bitWidth),
-		FrameOfRef:   minValue,
+		EncodingType:   EncodingALP,
+		Count:          int32(len(src)),
+		Exponent:       int8(scale.exponent),
+		Factor:         int8(scale.factor),
+		BitWidth:       uint8(bitWidth),
+		FrameOfRef:     minValue,
+		ExceptionCount: int32(len(exceptions)),
+		ValueType:      valueType,
 	}
 
 	// Combine metadata and src
@@ -121,127 +177,311 @@ func Encode(dst []byte, src []float64) []byte {
 
 // Decode decompresses ALP-encoded data
 func Decode(dst []float64, data []byte) []float64 {
+	return decode(dst, data)
+}
+
+func decode[F Float](dst []F, data []byte) []F {
 	if len(data) == 0 {
 		return dst[:0]
 	}
 
 	// Decode metadata
 	metadata := DecodeMetadata(data)
+	if metadata.ValueType != valueTypeOf[F]() || metadata.Stream {
+		return dst[:0]
+	}
 
 	switch metadata.EncodingType {
 	case EncodingNone:
 		return dst[:metadata.Count]
 
 	case EncodingConstant:
+		value := floatFromBits[F](math.Float64bits(metadata.ConstantValue))
 		for i := range dst {
-			dst[i] = metadata.ConstantValue
+			dst[i] = value
 		}
 		return dst[:metadata.Count]
 
 	case EncodingALP:
 		result := dst[:metadata.Count]
-		ints := unsafecast.Slice[int64](result)
-		bitpack.Unpack(ints, data[MetadataSize:], uint(metadata.BitWidth))
-
-		minValue := metadata.FrameOfRef
-		numValues := metadata.Count
-
-		// Use lookup table for power of 10.
-		invFactor := powersOf10[(10-metadata.Exponent+21)%21]
-
-		// Combined loop: add minValue and convert to float64 in one pass
-		// This reduces memory traffic and allows better optimization
-		i := int32(0)
-		for ; i+3 < numValues; i += 4 {
-			// Bounds check hint for the group of 4
-			_ = ints[i+3]
-			_ = result[i+3]
-
-			result[i] = float64(ints[i]+minValue) * invFactor
-			result[i+1] = float64(ints[i+1]+minValue) * invFactor
-			result[i+2] = float64(ints[i+2]+minValue) * invFactor
-			result[i+3] = float64(ints[i+3]+minValue) * invFactor
-		}
-		for ; i < numValues; i++ {
-			result[i] = float64(ints[i]+minValue) * invFactor
+		decodeALP(result, data, metadata, 0)
+		return result
+
+	case EncodingRD:
+		if isFloat32[F]() {
+			return dst[:0]
 		}
+		result := dst[:metadata.Count]
+		decodeRD(unsafecast.Slice[float64](result), data, metadata, 0)
+		return result
 
-		return dst[:metadata.Count]
+	case EncodingUncompressed:
+		result := dst[:metadata.Count]
+		decodeUncompressed(result, data, 0)
+		return result
 	default:
 		return dst[:0]
 	}
 }
 
-// findBestExponent analyzes the data and finds the best exponent for encoding
-func findBestExponent(data []float64) int {
-	if len(data) == 0 {
-		return 0
+// decodeALP unpacks the integers of an ALP-encoded block starting at index
+// start into result, converts them back to floats and patches in the
+// exceptions. If start is not a multiple of 8, result must have capacity for 7
+// more values, see unpackRange.
+func decodeALP[F Float](result []F, data []byte, metadata CompressionMetadata, start int) {
+	exceptionsSize := int(metadata.ExceptionCount) * exceptionSize[F]()
+	unpackFloats(result, data[MetadataSize+exceptionsSize:], start, metadata)
+	patchExceptions(result, data[MetadataSize:], int(metadata.ExceptionCount), start)
+}
+
+// unpackChunkSize is the number of integers unpacked at a time before they
+// are converted, so that they are still cached when they are converted.
+const unpackChunkSize = 1024
+
+// unpackFloats unpacks the bit-packed integers starting at index start into
+// result and converts them back to floats in place.
+func unpackFloats[F Float](result []F, src []byte, start int, metadata CompressionMetadata) {
+	bitWidth := uint(metadata.BitWidth)
+	for len(result) > 0 {
+		// All chunks but the first start at a multiple of 8, see unpackRange.
+		n := min(len(result), unpackChunkSize-start&7)
+		if isFloat32[F]() {
+			values := unsafecast.Slice[float32](result[:n])
+			ints := unsafecast.Slice[int32](result[:n])
+			unpackRange(ints, src, start, bitWidth)
+			decodeIntegersFloat32(values, ints, int32(metadata.FrameOfRef), metadata.scaling())
+		} else {
+			values := unsafecast.Slice[float64](result[:n])
+			ints := unsafecast.Slice[int64](result[:n])
+			unpackRange(ints, src, start, bitWidth)
+			decodeIntegers(values, ints, metadata.FrameOfRef, bitWidth, metadata.scaling())
+		}
+		result = result[n:]
+		start += n
 	}
+}
 
-	// Sample data if too large
-	sampleSize := min(len(data), SamplingSize)
+// encodeValue scales a value to round(v * 10^exponent * 10^-factor) and
+// reports whether the resulting integer reconstructs the exact bits of the
+// original value when decoded. Values such as NaN, ±Inf, -0.0 or values which
+// scale outside of the int64 range never reconstruct and have to be stored as
+// exceptions.
+func encodeValue(v float64, s scaling) (int64, bool) {
+	scaled := math.Round(v * powersOf10[s.exponent] * inversePowersOf10[s.factor])
+	if !(scaled >= math.MinInt64 && scaled < math.MaxInt64) {
+		return 0, false
+	}
+	intValue := int64(scaled)
+	return intValue, math.Float64bits(decodeValue(intValue, s)) == math.Float64bits(v)
+}
 
-	bestExponent := 0
-	minBitWidth := 64
+// decodeValue converts an integer back to a float64 value using the same
+// arithmetic as decodeIntegers.
+func decodeValue(v int64, s scaling) float64 {
+	return float64(v) * powersOf10[s.factor] * inversePowersOf10[s.exponent]
+}
 
-	// Try different exponents
-	for exp := MinExponent; exp <= MaxExponent; exp++ {
-		factor := powersOf10[exp+10]
-		invFactor := powersOf10[(10-exp+21)%21]
-		maxBits := 0
-		valid := true
+// decodeIntegers adds minValue to the integers, which are packed with
+// bitWidth bits, and converts them back to float64 in one pass. Most values
+// are converted with SIMD instructions where available.
+func decodeIntegers(result []float64, ints []int64, minValue int64, bitWidth uint, s scaling) {
+	var (
+		factor = powersOf10[s.factor]
+		invExp = inversePowersOf10[s.exponent]
+		n      = decodeIntegersArch(result, ints, minValue, bitWidth, factor, invExp)
+	)
+	decodeIntegersGeneric(result[n:], ints[n:], minValue, factor, invExp)
+}
 
-		for i := range sampleSize {
-			idx := i * len(data) / sampleSize
-			original := data[idx]
-			scaled := original * factor
+func decodeIntegersGeneric(result []float64, ints []int64, minValue int64, factor, invExp float64) {
+	numValues := len(result)
+	_ = ints[:numValues]
 
-			// Check if conversion is lossless
-			rounded := math.Round(scaled)
-			intValue := int64(rounded)
+	i := 0
+	for ; i+3 < numValues; i += 4 {
+		// Bounds check hint for the group of 4
+		_ = ints[i+3]
+		_ = result[i+3]
 
-			// Reconstruct and check if lossless using same method as decompression
-			reconstructed := float64(intValue) * invFactor
-			relativeError := math.Abs(original - reconstructed)
-			if original != 0 {
-				relativeError /= math.Abs(original)
-			}
+		result[i] = float64(ints[i]+minValue) * factor * invExp
+		result[i+1] = float64(ints[i+1]+minValue) * factor * invExp
+		result[i+2] = float64(ints[i+2]+minValue) * factor * invExp
+		result[i+3] = float64(ints[i+3]+minValue) * factor * invExp
+	}
+	for ; i < numValues; i++ {
+		result[i] = float64(ints[i]+minValue) * factor * invExp
+	}
+}
 
-			if relativeError > 1e-12 {
-				// Not lossless at this exponent - skip this exponent entirely
-				valid = false
-				break
-			}
+// encodeToIntegers converts values to integers using the scaling. Values
+// which cannot be reconstructed are returned as exceptions and their integers
+// are replaced with the first successfully encoded value so that they do not
+// affect the frame-of-reference or the bit-width. The returned slices are
+// scratch buffers of e.
+func encodeToIntegers[F Float](e *Encoder, src []F, s scaling) ([]int64, []uint32) {
+	var (
+		result     = slices.Grow(e.ints[:0], len(src))[:len(src)]
+		exceptions = e.exceptions[:0]
+		fill       int64
+		filled     bool
+	)
+	for i, v := range src {
+		intValue, ok := encodeFloat(v, s)
+		if !ok {
+			exceptions = append(exceptions, uint32(i))
+			continue
+		}
+		if !filled {
+			fill, filled = intValue, true
+		}
+		result[i] = intValue
+	}
+	for _, pos := range exceptions {
+		result[pos] = fill
+	}
+	e.ints, e.exceptions = result, exceptions
+	return result, exceptions
+}
 
-			bits := CalculateBitWidthSigned(intValue)
-			if bits > maxBits {
-				maxBits = bits
-			}
+// findBounds finds the frame-of-reference window [lo, hi] for the integers.
+// Values outside of the window are stored as exceptions, which allows a few
+// spikes to be patched instead of widening every packed value. exceptionSize
+// is the encoded size in bytes of a single exception.
+func (e *Encoder) findBounds(values []int64, exceptionSize int) (lo, hi int64) {
+	lo, hi = slices.Min(values), slices.Max(values)
+	fullWidth := CalculateBitWidth(uint64(hi - lo))
+	if fullWidth <= 1 {
+		return lo, hi
+	}
+
+	sampleSize := min(len(values), SamplingSize)
+	sample := slices.Grow(e.sample[:0], sampleSize)[:sampleSize]
+	e.sample = sample
+	for i := range sample {
+		sample[i] = values[i*len(values)/sampleSize]
+	}
+	maxOutliers := sampleSize / 8
+	if !hasDenseWindow(sample, sampleSize-maxOutliers) {
+		return lo, hi
+	}
 
-			// If bits are too large, this exponent won't be good
-			if maxBits > 63 {
-				valid = false
-				break
+	// Find the narrowest bit-width for which a window covers all but at
+	// most 1/8 of the sorted sample, trading packed bits for exceptions.
+	slices.Sort(sample)
+	var (
+		exceptionBits = exceptionSize * 8
+		bestLo        = sample[0]
+		bestHi        = sample[sampleSize-1]
+		sampleWidth   = CalculateBitWidth(uint64(bestHi - bestLo))
+		minCost       = sampleSize * sampleWidth
+	)
+	for bitWidth := sampleWidth - 1; bitWidth >= 1; bitWidth-- {
+		// Slide a window of values which fit into bitWidth bits over the
+		// sample and keep the one covering the most values.
+		var (
+			limit    = uint64(1) << bitWidth
+			covered  = 0
+			from, to int
+		)
+		for i, j := 0, 0; j < sampleSize; j++ {
+			for uint64(sample[j]-sample[i]) >= limit {
+				i++
+			}
+			if j-i+1 > covered {
+				covered, from, to = j-i+1, i, j
 			}
 		}
 
-		if valid && maxBits > 0 && maxBits < minBitWidth {
-			minBitWidth = maxBits
-			bestExponent = exp
+		outliers := sampleSize - covered
+		if outliers > maxOutliers {
+			break
+		}
+		if cost := sampleSize*bitWidth + outliers*exceptionBits; cost < minCost {
+			minCost, bestLo, bestHi = cost, sample[from], sample[to]
+		}
+	}
+	if bestLo == lo && bestHi == hi {
+		return lo, hi
+	}
+
+	// The sample can miss outliers, so only use the window if it is
+	// smaller than the full range on the whole input.
+	outliers := 0
+	for _, v := range values {
+		if v < bestLo || v > bestHi {
+			outliers++
+		}
+	}
+	windowCost := len(values)*CalculateBitWidth(uint64(bestHi-bestLo)) + outliers*exceptionBits
+	if windowCost >= len(values)*fullWidth {
+		return lo, hi
+	}
+	return bestLo, bestHi
+}
+
+// hasDenseWindow is a quick check whether at least minCovered values of the
+// sample fit into a window needing one bit less than the whole sample. Such a
+// window spans at most three of the four quarters of the sample's bit range.
+func hasDenseWindow(sample []int64, minCovered int) bool {
+	lo, hi := slices.Min(sample), slices.Max(sample)
+	shift := CalculateBitWidth(uint64(hi-lo)) - 2
+	if shift < 0 {
+		return false
+	}
+
+	var quarters [4]int
+	for _, v := range sample {
+		quarters[uint64(v-lo)>>shift]++
+	}
+	return quarters[0]+quarters[1]+quarters[2] >= minCovered ||
+		quarters[1]+quarters[2]+quarters[3] >= minCovered
+}
+
+// applyFrameOfReference subtracts lo from all values in place. Values outside
+// of [lo, hi] are merged into the sorted exception positions and zeroed. The
+// returned positions are a scratch buffer of e.
+func (e *Encoder) applyFrameOfReference(values []int64, exceptions []uint32, lo, hi int64) []uint32 {
+	var (
+		merged = e.positions[:0]
+		next   = 0
+	)
+	for i, v := range values {
+		isException := next < len(exceptions) && exceptions[next] == uint32(i)
+		if isException {
+			next++
 		}
+		if isException || v < lo || v > hi {
+			merged = append(merged, uint32(i))
+			values[i] = 0
+			continue
+		}
+		values[i] = v - lo
 	}
+	e.positions = merged
+	return merged
+}
 
-	return bestExponent
+// encodeExceptions writes the exception positions followed by the raw bits
+// of the exception values.
+func encodeExceptions[F Float](dst []byte, src []F, positions []uint32) {
+	values := dst[len(positions)*4:]
+	for i, pos := range positions {
+		binary.LittleEndian.PutUint32(dst[i*4:], pos)
+		putFloat(values[i*valueSize[F]():], src[pos])
+	}
 }
 
-// encodeToIntegers converts float64 values to integers using the factor
-func encodeToIntegers(src []float64, factor float64) []int64 {
-	result := make([]int64, len(src))
-	for i, v := range src {
-		scaled := v * factor
-		result[i] = int64(math.Round(scaled))
+// patchExceptions overwrites decoded values with the stored exceptions. dst
+// holds the values starting at index start, and exceptions outside of dst are
+// skipped.
+func patchExceptions[F Float](dst []F, src []byte, count int, start int) {
+	values := src[count*4:]
+	for i := range count {
+		pos := int(binary.LittleEndian.Uint32(src[i*4:])) - start
+		if uint(pos) < uint(len(dst)) {
+			dst[pos] = readFloat[F](values[i*valueSize[F]():])
+		}
 	}
-	return result
 }
 
 // DecompressValues decompresses ALP-encoded data
@@ -258,43 +498,52 @@ func DecompressValues(result []float64, src []byte, metadata CompressionMetadata
 			result[i] = metadata.ConstantValue
 		}
 	case EncodingALP:
-		// Unpack src
-		packedData := src[MetadataSize:]
-		unpacked := unsafecast.Slice[int64](result)
-		bitpack.Unpack(unpacked, packedData, uint(metadata.BitWidth))
-
-		// Reverse frame-of-reference and convert back to float64 in one pass
-		minValue := metadata.FrameOfRef
-		invFactor := powersOf10[(10-metadata.Exponent+21)%21]
-		numValues := metadata.Count
-
-		// Unroll loop for better performance
-		i := int32(0)
-		for ; i+3 < numValues; i += 4 {
-			// Bounds check hint for the group of 4
-			_ = unpacked[i+3]
-			_ = result[i+3]
-
-			result[i] = float64(unpacked[i]+minValue) * invFactor
-			result[i+1] = float64(unpacked[i+1]+minValue) * invFactor
-			result[i+2] = float64(unpacked[i+2]+minValue) * invFactor
-			result[i+3] = float64(unpacked[i+3]+minValue) * invFactor
-		}
-		for ; i < numValues; i++ {
-			result[i] = float64(unpacked[i]+minValue) * invFactor
-		}
+		decodeALP(result[:metadata.Count], src, metadata, 0)
+	case EncodingRD:
+		decodeRD(result[:metadata.Count], src, metadata, 0)
+	case EncodingUncompressed:
+		decodeUncompressed(result[:metadata.Count], src, 0)
+	}
+}
+
+// encodeUncompressed stores the raw bits of the values after the metadata.
+func encodeUncompressed[F Float](dst []byte, src []F) []byte {
+	size := valueSize[F]()
+	totalSize := MetadataSize + len(src)*size
+	if cap(dst) < totalSize {
+		dst = make([]byte, totalSize)
+	}
+	dst = dst[:totalSize]
+	encodeMetadata(dst, CompressionMetadata{
+		EncodingType: EncodingUncompressed,
+		Count:        int32(len(src)),
+		ValueType:    valueTypeOf[F](),
+	})
+	for i, v := range src {
+		putFloat(dst[MetadataSize+i*size:], v)
 	}
+	return dst
 }
 
-// isConstant checks if all values in the array are the same
-func isConstant(data []float64) bool {
+// decodeUncompressed reads the raw bits of the values after the metadata,
+// starting at index start.
+func decodeUncompressed[F Float](result []F, data []byte, start int) {
+	size := valueSize[F]()
+	data = data[MetadataSize+start*size:]
+	for i := range result {
+		result[i] = readFloat[F](data[i*size:])
+	}
+}
+
+// isConstant checks if all values in the array have the same bits
+func isConstant[F Float](data []F) bool {
 	if len(data) <= 1 {
 		return true
 	}
 
-	first := data[0]
+	first := floatBits(data[0])
 	for _, v := range data[1:] {
-		if v != first {
+		if floatBits(v) != first {
 			return false
 		}
 	}
@@ -309,6 +558,15 @@ func encodeMetadata(buf []byte, metadata CompressionMetadata) {
 	buf[6] = metadata.BitWidth
 	binary.LittleEndian.PutUint64(buf[7:15], uint64(metadata.FrameOfRef))
 	binary.LittleEndian.PutUint64(buf[15:23], math.Float64bits(metadata.ConstantValue))
+	binary.LittleEndian.PutUint32(buf[23:27], uint32(metadata.ExceptionCount))
+	buf[27] = byte(metadata.Factor)
+	buf[28] = byte(metadata.ValueType)
+	if metadata.Checksum {
+		buf[28] |= checksumFlag
+	}
+	if metadata.Stream {
+		buf[28] |= streamFlag
+	}
 }
 
 // DecodeMetadata decodes compression metadata from bytes
@@ -318,13 +576,93 @@ func DecodeMetadata(data []byte) CompressionMetadata {
 	}
 
 	return CompressionMetadata{
-		EncodingType:  EncodingType(data[0]),
-		Count:         int32(binary.LittleEndian.Uint32(data[1:5])),
-		Exponent:      int8(data[5]),
-		BitWidth:      data[6],
-		FrameOfRef:    int64(binary.LittleEndian.Uint64(data[7:15])),
-		ConstantValue: math.Float64frombits(binary.LittleEndian.Uint64(data[15:23])),
+		EncodingType:   EncodingType(data[0]),
+		Count:          int32(binary.LittleEndian.Uint32(data[1:5])),
+		Exponent:       int8(data[5]),
+		BitWidth:       data[6],
+		FrameOfRef:     int64(binary.LittleEndian.Uint64(data[7:15])),
+		ConstantValue:  math.Float64frombits(binary.LittleEndian.Uint64(data[15:23])),
+		ExceptionCount: int32(binary.LittleEndian.Uint32(data[23:27])),
+		Factor:         int8(data[27]),
+		ValueType:      ValueType(data[28] &^ (checksumFlag | streamFlag)),
+		Checksum:       data[28]&checksumFlag != 0,
+		Stream:         data[28]&streamFlag != 0,
+	}
+}
+
+// scaling returns the exponent and factor of the metadata.
+func (m CompressionMetadata) scaling() scaling {
+	return scaling{exponent: int(m.Exponent), factor: int(m.Factor)}
+}
+
+// isFloat32 reports whether F is float32.
+func isFloat32[F Float]() bool {
+	return unsafe.Sizeof(F(0)) == 4
+}
+
+// valueTypeOf returns the ValueType of F.
+func valueTypeOf[F Float]() ValueType {
+	if isFloat32[F]() {
+		return ValueTypeFloat32
+	}
+	return ValueTypeFloat64
+}
+
+// valueSize returns the size in bytes of a value of type F.
+func valueSize[F Float]() int {
+	return int(unsafe.Sizeof(F(0)))
+}
+
+// exceptionSize returns the size in bytes of a single exception of type F.
+func exceptionSize[F Float]() int {
+	if isFloat32[F]() {
+		return ExceptionSizeFloat32
+	}
+	return ExceptionSize
+}
+
+// floatBits returns the IEEE 754 bits of v.
+func floatBits[F Float](v F) uint64 {
+	if isFloat32[F]() {
+		return uint64(math.Float32bits(float32(v)))
+	}
+	return math.Float64bits(float64(v))
+}
+
+// floatFromBits returns the value with the IEEE 754 bits in the low bits of
+// bits.
+func floatFromBits[F Float](bits uint64) F {
+	if isFloat32[F]() {
+		return F(math.Float32frombits(uint32(bits)))
+	}
+	return F(math.Float64frombits(bits))
+}
+
+// putFloat writes the raw bits of v to dst in little-endian order.
+func putFloat[F Float](dst []byte, v F) {
+	if isFloat32[F]() {
+		binary.LittleEndian.PutUint32(dst, uint32(floatBits(v)))
+		return
+	}
+	binary.LittleEndian.PutUint64(dst, floatBits(v))
+}
+
+// readFloat reads the raw bits of a value from src in little-endian order.
+func readFloat[F Float](src []byte) F {
+	if isFloat32[F]() {
+		return floatFromBits[F](uint64(binary.LittleEndian.Uint32(src)))
+	}
+	return floatFromBits[F](binary.LittleEndian.Uint64(src))
+}
+
+// encodeFloat scales a value of type F to an integer, see encodeValue and
+// encodeValueFloat32.
+func encodeFloat[F Float](v F, s scaling) (int64, bool) {
+	if isFloat32[F]() {
+		intValue, ok := encodeValueFloat32(float32(v), s)
+		return int64(intValue), ok
 	}
+	return encodeValue(float64(v), s)
 }
 
 // CompressionRatio calculates the compression ratio
@@ -0,0 +1,104 @@
+package alp
+
+import "math"
+
+const (
+	// MaxExponentFloat32 is the maximum exponent to try for encoding float32
+	// values. Larger powers of 10 exceed the precision of float32.
+	MaxExponentFloat32 = 10
+	// MaxFactorFloat32 is the maximum factor to try for encoding float32 values.
+	MaxFactorFloat32 = 10
+	// ExceptionSizeFloat32 is the size in bytes of a single float32 exception:
+	// a uint32 position followed by the raw bits of the float32 value.
+	ExceptionSizeFloat32 = 4 + 4
+)
+
+// EncodeFloat32 compresses an array of float32 values using ALP. Values are
+// scaled to 32-bit integers, and values which cannot be scaled are stored as
+// exceptions or left uncompressed.
+func EncodeFloat32(dst []byte, src []float32) []byte {
+	var e Encoder
+	return encode(&e, dst, src)
+}
+
+// DecodeFloat32 decompresses ALP-encoded float32 data
+func DecodeFloat32(dst []float32, data []byte) []float32 {
+	return decode(dst, data)
+}
+
+// StreamEncodeFloat32 encodes float32 values using ALP with block-based
+// packing for streaming decode
+func StreamEncodeFloat32(dst []byte, src []float32, blockSize int) []byte {
+	return streamEncode(dst, src, blockSize)
+}
+
+// StreamDecoderFloat32 decodes float32 values encoded with StreamEncodeFloat32.
+type StreamDecoderFloat32 struct {
+	streamDecoder[float32]
+}
+
+// StreamEncoderFloat32 incrementally encodes float32 values into the stream
+// format of StreamEncodeFloat32, see StreamEncoder.
+type StreamEncoderFloat32 struct {
+	streamEncoder[float32]
+}
+
+// NewStreamEncoderFloat32 returns an encoder writing blocks of blockSize
+// float32 values, see NewStreamEncoder.
+func NewStreamEncoderFloat32(blockSize int) *StreamEncoderFloat32 {
+	e := &StreamEncoderFloat32{}
+	e.init(blockSize)
+	return e
+}
+
+// encodeValueFloat32 scales a value to round(v * 10^exponent * 10^-factor)
+// and reports whether the resulting integer reconstructs the exact bits of the
+// original value when decoded. Scaling uses float64 arithmetic since inverse
+// powers of 10 are too imprecise in float32 to reconstruct most decimals.
+func encodeValueFloat32(v float32, s scaling) (int32, bool) {
+	scaled := math.Round(float64(v) * powersOf10[s.exponent] * inversePowersOf10[s.factor])
+	if !(scaled >= math.MinInt32 && scaled <= math.MaxInt32) {
+		return 0, false
+	}
+	intValue := int32(scaled)
+	return intValue, math.Float32bits(decodeValueFloat32(intValue, s)) == math.Float32bits(v)
+}
+
+// decodeValueFloat32 converts an integer back to a float32 value using the
+// same arithmetic as decodeIntegersFloat32.
+func decodeValueFloat32(v int32, s scaling) float32 {
+	return float32(float64(v) * powersOf10[s.factor] * inversePowersOf10[s.exponent])
+}
+
+// decodeIntegersFloat32 adds minValue to the integers and converts them back
+// to float32 in one pass. Additions wrap around, so integers packed relative
+// to minValue with up to 32 bits are restored correctly. Most values are
+// converted with SIMD instructions where available.
+func decodeIntegersFloat32(result []float32, ints []int32, minValue int32, s scaling) {
+	var (
+		factor = powersOf10[s.factor]
+		invExp = inversePowersOf10[s.exponent]
+		n      = decodeIntegersFloat32Arch(result, ints, minValue, factor, invExp)
+	)
+	decodeIntegersFloat32Generic(result[n:], ints[n:], minValue, factor, invExp)
+}
+
+func decodeIntegersFloat32Generic(result []float32, ints []int32, minValue int32, factor, invExp float64) {
+	numValues := len(result)
+	_ = ints[:numValues]
+
+	i := 0
+	for ; i+3 < numValues; i += 4 {
+		// Bounds check hint for the group of 4
+		_ = ints[i+3]
+		_ = result[i+3]
+
+		result[i] = float32(float64(ints[i]+minValue) * factor * invExp)
+		result[i+1] = float32(float64(ints[i+1]+minValue) * factor * invExp)
+		result[i+2] = float32(float64(ints[i+2]+minValue) * factor * invExp)
+		result[i+3] = float32(float64(ints[i+3]+minValue) * factor * invExp)
+	}
+	for ; i < numValues; i++ {
+		result[i] = float32(float64(ints[i]+minValue) * factor * invExp)
+	}
+}
@@ -0,0 +1,253 @@
+package alp
+
+import (
+	"cmp"
+	"encoding/binary"
+	"math"
+	"slices"
+
+	"github.com/parquet-go/bitpack"
+	"github.com/parquet-go/bitpack/unsafecast"
+)
+
+// ALP-RD (real doubles) is used for values which cannot be losslessly scaled
+// to integers. The bits of each value are split into a left part, which is
+// dictionary encoded, and a right part, which is bit-packed as is.
+//
+// The encoded layout after the metadata is:
+//
+//	dictionary size (uint8)
+//	dictionary entries (uint16 each)
+//	exception positions (uint32 each)
+//	exception left parts (uint16 each)
+//	packed dictionary indexes
+//	packed right parts
+const (
+	// RDExceptionSize is the size in bytes of a single ALP-RD exception: a
+	// uint32 position followed by the uint16 left part of the value.
+	RDExceptionSize = 4 + 2
+
+	// rdMaxLeftBitWidth is the maximum number of bits in the left part.
+	rdMaxLeftBitWidth = 16
+	// rdMinRightBitWidth is the minimum number of bits in the right part.
+	rdMinRightBitWidth = 64 - rdMaxLeftBitWidth
+	// rdMaxDictionarySize is the maximum number of left parts in the dictionary.
+	rdMaxDictionarySize = 8
+	// rdDecodeChunkSize is the number of dictionary indexes unpacked at once.
+	rdDecodeChunkSize = 1024
+)
+
+// rdSplit describes how the bits of values are split by ALP-RD.
+type rdSplit struct {
+	rightBitWidth int
+	dictionary    []uint16
+	// cost is the estimated size in bits of the sampled values.
+	cost int
+}
+
+// findBestRDSplit finds the split position and dictionary which minimize the
+// estimated encoded size of the sampled values. The dictionary of the split
+// is a scratch buffer of e.
+func (e *Encoder) findBestRDSplit(data []float64) rdSplit {
+	sampleSize := min(len(data), SamplingSize)
+	lefts := slices.Grow(e.lefts[:0], sampleSize)[:sampleSize]
+	e.lefts = lefts
+	for i := range lefts {
+		bits := math.Float64bits(data[i*len(data)/sampleSize])
+		lefts[i] = uint16(bits >> rdMinRightBitWidth)
+	}
+	// Shifting preserves the order, so the sample only has to be sorted once.
+	slices.Sort(lefts)
+
+	best := rdSplit{cost: math.MaxInt}
+	for leftBitWidth := 1; leftBitWidth <= rdMaxLeftBitWidth; leftBitWidth++ {
+		dictionary, covered := e.rdDictionary(lefts, rdMaxLeftBitWidth-leftBitWidth)
+		indexBitWidth := CalculateBitWidth(uint64(len(dictionary) - 1))
+		exceptions := sampleSize - covered
+
+		cost := sampleSize*(64-leftBitWidth+indexBitWidth) + exceptions*RDExceptionSize*8
+		if cost < best.cost {
+			e.best = append(e.best[:0], dictionary...)
+			best = rdSplit{
+				rightBitWidth: 64 - leftBitWidth,
+				dictionary:    e.best,
+				cost:          cost,
+			}
+		}
+	}
+	return best
+}
+
+// rdDictionary returns the most frequent values of a sorted slice after
+// shifting them right, along with the number of values they cover. The
+// dictionary is a scratch buffer of e.
+func (e *Encoder) rdDictionary(sorted []uint16, shift int) ([]uint16, int) {
+	runs := e.runs[:0]
+	for _, v := range sorted {
+		v >>= shift
+		if len(runs) > 0 && runs[len(runs)-1].value == v {
+			runs[len(runs)-1].count++
+			continue
+		}
+		runs = append(runs, rdRun{value: v, count: 1})
+	}
+	slices.SortStableFunc(runs, func(a, b rdRun) int {
+		return cmp.Compare(b.count, a.count)
+	})
+	e.runs = runs
+
+	var (
+		dictionary = e.dictionary[:0]
+		covered    = 0
+	)
+	for _, r := range runs[:min(len(runs), rdMaxDictionarySize)] {
+		dictionary = append(dictionary, r.value)
+		covered += r.count
+	}
+	e.dictionary = dictionary
+	return dictionary, covered
+}
+
+// rdRun is a run of equal left parts in a sorted sample.
+type rdRun struct {
+	value uint16
+	count int
+}
+
+// encodeRD encodes the values using ALP-RD with the given split.
+func (e *Encoder) encodeRD(dst []byte, src []float64, split rdSplit) []byte {
+	var (
+		rightBitWidth = split.rightBitWidth
+		rightMask     = uint64(1)<<rightBitWidth - 1
+		indexBitWidth = CalculateBitWidth(uint64(len(split.dictionary) - 1))
+		indexes       = slices.Grow(e.ints[:0], len(src))[:len(src)]
+		rights        = slices.Grow(e.rights[:0], len(src))[:len(src)]
+		exceptions    = e.exceptions[:0]
+	)
+	for i, v := range src {
+		bits := math.Float64bits(v)
+		rights[i] = bits & rightMask
+
+		index := slices.Index(split.dictionary, uint16(bits>>rightBitWidth))
+		if index < 0 {
+			exceptions = append(exceptions, uint32(i))
+			index = 0
+		}
+		indexes[i] = int64(index)
+	}
+	e.ints, e.rights, e.exceptions = indexes, rights, exceptions
+
+	var (
+		dictionarySize = 1 + len(split.dictionary)*2
+		exceptionsSize = len(exceptions) * RDExceptionSize
+		indexesSize    = bitpack.ByteCount(uint(len(src) * indexBitWidth))
+		rightsSize     = bitpack.ByteCount(uint(len(src)*rightBitWidth)) + bitpack.PaddingInt64
+		totalSize      = MetadataSize + dictionarySize + exceptionsSize + indexesSize + rightsSize
+	)
+	if cap(dst) < totalSize {
+		dst = make([]byte, totalSize)
+	}
+	dst = dst[:totalSize]
+
+	encodeMetadata(dst, CompressionMetadata{
+		EncodingType:   EncodingRD,
+		Count:          int32(len(src)),
+		BitWidth:       uint8(rightBitWidth),
+		ExceptionCount: int32(len(exceptions)),
+	})
+
+	buf := dst[MetadataSize:]
+	buf[0] = byte(len(split.dictionary))
+	for i, left := range split.dictionary {
+		binary.LittleEndian.PutUint16(buf[1+i*2:], left)
+	}
+	buf = buf[dictionarySize:]
+
+	lefts := buf[len(exceptions)*4:]
+	for i, pos := range exceptions {
+		binary.LittleEndian.PutUint32(buf[i*4:], pos)
+		binary.LittleEndian.PutUint16(lefts[i*2:], uint16(math.Float64bits(src[pos])>>rightBitWidth))
+	}
+	buf = buf[exceptionsSize:]
+
+	bitpack.Pack(buf, indexes, uint(indexBitWidth))
+	bitpack.Pack(buf[indexesSize:], unsafecast.Slice[int64](rights), uint(rightBitWidth))
+	clear(dst[totalSize-bitpack.PaddingInt64:])
+	return dst
+}
+
+// decodeRD decodes the ALP-RD encoded values starting at index start into
+// result. If start is not a multiple of 8, result must have capacity for 7
+// more values, see unpackRange.
+func decodeRD(result []float64, data []byte, metadata CompressionMetadata, start int) {
+	var (
+		rightBitWidth  = uint(metadata.BitWidth)
+		buf            = data[MetadataSize:]
+		dictionarySize = int(buf[0])
+		dictionary     [rdMaxDictionarySize]uint64
+	)
+	for i := range dictionarySize {
+		dictionary[i] = uint64(binary.LittleEndian.Uint16(buf[1+i*2:])) << rightBitWidth
+	}
+	buf = buf[1+dictionarySize*2:]
+
+	exceptionCount := int(metadata.ExceptionCount)
+	exceptions := buf[:exceptionCount*RDExceptionSize]
+	buf = buf[exceptionCount*RDExceptionSize:]
+
+	var (
+		indexBitWidth = CalculateBitWidth(uint64(dictionarySize - 1))
+		indexesSize   = bitpack.ByteCount(uint(int(metadata.Count) * indexBitWidth))
+		bits          = unsafecast.Slice[uint64](result)
+	)
+	unpackRange(unsafecast.Slice[int64](result), buf[indexesSize:], start, rightBitWidth)
+
+	// Unpack the indexes in chunks to avoid allocating a second buffer. The
+	// chunk has room for the values unpacked before start, see unpackRange.
+	var indexes [rdDecodeChunkSize + 7]int64
+	for from := 0; from < len(result); from += rdDecodeChunkSize {
+		chunk := indexes[:min(rdDecodeChunkSize, len(result)-from)]
+		unpackRange(chunk, buf, start+from, uint(indexBitWidth))
+		for i, index := range chunk {
+			bits[from+i] |= dictionary[index]
+		}
+	}
+
+	lefts := exceptions[exceptionCount*4:]
+	rightMask := uint64(1)<<rightBitWidth - 1
+	for i := range exceptionCount {
+		pos := int(binary.LittleEndian.Uint32(exceptions[i*4:])) - start
+		if uint(pos) >= uint(len(bits)) {
+			continue
+		}
+		left := uint64(binary.LittleEndian.Uint16(lefts[i*2:]))
+		bits[pos] = left<<rightBitWidth | bits[pos]&rightMask
+	}
+}
+
+// rdAt returns the ALP-RD encoded value at index i.
+func rdAt(data []byte, metadata CompressionMetadata, i int) float64 {
+	var (
+		rightBitWidth  = uint(metadata.BitWidth)
+		buf            = data[MetadataSize:]
+		dictionarySize = int(buf[0])
+		dictionary     = buf[1:]
+	)
+	buf = buf[1+dictionarySize*2:]
+
+	exceptionCount := int(metadata.ExceptionCount)
+	exceptions := buf[:exceptionCount*RDExceptionSize]
+	buf = buf[exceptionCount*RDExceptionSize:]
+
+	var (
+		indexBitWidth = uint(CalculateBitWidth(uint64(dictionarySize - 1)))
+		indexesSize   = bitpack.ByteCount(uint(int(metadata.Count)) * indexBitWidth)
+		index         = unpackAt(buf, i, indexBitWidth)
+		right         = unpackAt(buf[indexesSize:], i, rightBitWidth)
+		left          = uint64(binary.LittleEndian.Uint16(dictionary[index*2:]))
+	)
+	if pos := exceptionIndex(exceptions, exceptionCount, i); pos >= 0 {
+		left = uint64(binary.LittleEndian.Uint16(exceptions[exceptionCount*4+pos*2:]))
+	}
+	return math.Float64frombits(left<<rightBitWidth | right)
+}
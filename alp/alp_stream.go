@@ -1,144 +1,251 @@
 package alp
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"io"
+	"slices"
 
 	"github.com/parquet-go/bitpack"
-	"github.com/parquet-go/bitpack/unsafecast"
 )
 
-// StreamEncode encodes float64 values using ALP with block-based packing for streaming decode
-func StreamEncode(dst []byte, src []float64, blockSize int) []byte {
-	if len(src) == 0 {
-		if cap(dst) < MetadataSize {
-			dst = make([]byte, MetadataSize)
-		}
-		dst = dst[:MetadataSize]
+// Streams split values into blocks which are encoded with their own
+// parameters, so that a region with a wide range of values does not inflate
+// the other blocks. A stream starts with the metadata, flagged as a stream,
+// followed by the block size and an index of the byte offsets of the blocks:
+//
+//	metadata (MetadataSize bytes)
+//	block size (uint32)
+//	block offsets (uint32 each, relative to the first block)
+//	blocks
+//	padding (bitpack.PaddingInt64 bytes)
+//
+// Each block starts with a header holding its encoding, exponent, factor,
+// bit-width, exception count and frame-of-reference, followed by:
+//
+//	ALP:          exception positions (uint32 each) and values, packed integers
+//	constant:     nothing, the frame-of-reference holds the bits of the value
+//	uncompressed: the raw bits of the values
+const (
+	// StreamHeaderSize is the size in bytes of the metadata and the block
+	// size at the start of a stream.
+	StreamHeaderSize = MetadataSize + 4
+	// MaxStreamBlockSize is the maximum block size of a stream.
+	MaxStreamBlockSize = 1 << 16
+	// DefaultBlockSize is the block size of stream encoders which were not
+	// given one, following the vectors of the ALP paper.
+	DefaultBlockSize = VectorSize
 
-		encodeMetadata(dst, CompressionMetadata{
-			EncodingType: EncodingNone,
-			Count:        0,
-		})
-		return dst
-	}
+	// streamFlag is set in the value type byte of the metadata of streams,
+	// distinguishing them from blocks produced by Encode.
+	streamFlag = 0x40
+	// blockHeaderSize is the size in bytes of the header of a block.
+	blockHeaderSize = 16
+)
 
-	// Find global encoding parameters
-	exponent := findBestExponent(src)
-	factor := powersOf10[exponent+10]
+// ErrBlockSize is returned by stream decoders when the block size recorded in
+// a stream does not match the expected block size.
+var ErrBlockSize = errors.New("block size mismatch")
 
-	// Convert all to integers with global exponent
-	forValues := encodeToIntegers(src, factor)
+// StreamEncode encodes float64 values using ALP in blocks of blockSize values,
+// each with its own exponent, factor, frame-of-reference and bit-width. The
+// block size is recorded in the stream and must be between 1 and
+// MaxStreamBlockSize. Blocks of VectorSize values follow the ALP paper.
+func StreamEncode(dst []byte, src []float64, blockSize int) []byte {
+	return streamEncode(dst, src, blockSize)
+}
 
-	// Find global frame-of-reference
-	minValue := forValues[0]
-	for _, v := range forValues {
-		minValue = min(minValue, v)
-	}
+func streamEncode[F Float](dst []byte, src []F, blockSize int) []byte {
+	checkBlockSize(blockSize)
+	numBlocks := (len(src) + blockSize - 1) / blockSize
+	dst = appendStreamHeader[F](dst[:0], len(src), blockSize)
+	headerSize := len(dst)
 
-	// Apply frame-of-reference and find global bit-width
-	bitWidth := 0
-	for i, v := range forValues {
-		forValues[i] = v - minValue
-		bits := CalculateBitWidth(uint64(forValues[i]))
-		bitWidth = max(bitWidth, bits)
+	// Candidate scalings are sampled once for the whole input, and each
+	// block picks the best of them as in the ALP paper.
+	var (
+		e          Encoder
+		candidates = findCandidates(&e, src)
+	)
+	for i := range numBlocks {
+		from := i * blockSize
+		binary.LittleEndian.PutUint32(dst[StreamHeaderSize+i*4:], uint32(len(dst)-headerSize))
+		dst = appendBlock(&e, dst, src[from:min(from+blockSize, len(src))], candidates)
 	}
 
-	// Calculate total packed size.
-	blockSizeBytes := bitpack.ByteCount(uint(blockSize * bitWidth))
-	totalBlocks := (len(forValues) + blockSize - 1) / blockSize
-	packedSize := blockSizeBytes*totalBlocks + bitpack.PaddingInt64
+	// Pad the stream so that the last block can be unpacked.
+	return append(dst, make([]byte, bitpack.PaddingInt64)...)
+}
 
-	// Create output buffer: metadata + packed blocks
-	totalSize := MetadataSize + packedSize
-	if cap(dst) < totalSize {
-		dst = make([]byte, totalSize)
+// checkBlockSize panics if blockSize is not a valid block size of a stream.
+func checkBlockSize(blockSize int) {
+	if blockSize < 1 || blockSize > MaxStreamBlockSize {
+		panic(fmt.Sprintf("alp: block size %d out of range [1:%d]", blockSize, MaxStreamBlockSize))
 	}
-	dst = dst[:totalSize]
+}
 
-	encodeMetadata(dst, CompressionMetadata{
-		EncodingType: EncodingALP,
-		Count:        int32(len(src)),
-		Exponent:     int8(exponent),
-		BitWidth:     uint8(bitWidth),
-		FrameOfRef:   minValue,
+// appendStreamHeader appends the header of a stream of count values to dst,
+// followed by a block index which has to be filled in by the caller.
+func appendStreamHeader[F Float](dst []byte, count, blockSize int) []byte {
+	var (
+		offset       = len(dst)
+		numBlocks    = (count + blockSize - 1) / blockSize
+		headerSize   = StreamHeaderSize + numBlocks*4
+		encodingType = EncodingALP
+	)
+	if count == 0 {
+		encodingType = EncodingNone
+	}
+	dst = slices.Grow(dst, headerSize)[:offset+headerSize]
+	encodeMetadata(dst[offset:], CompressionMetadata{
+		EncodingType: encodingType,
+		Count:        int32(count),
+		ValueType:    valueTypeOf[F](),
+		Stream:       true,
 	})
+	binary.LittleEndian.PutUint32(dst[offset+MetadataSize:], uint32(blockSize))
+	return dst
+}
+
+// appendBlock encodes a block of values with the best of the candidate
+// scalings and appends it to dst. Constant blocks only store the value, and
+// blocks which do not compress are stored uncompressed.
+func appendBlock[F Float](e *Encoder, dst []byte, src []F, candidates []scaling) []byte {
+	header := CompressionMetadata{Count: int32(len(src))}
+	if isConstant(src) {
+		header.EncodingType = EncodingConstant
+		header.FrameOfRef = int64(floatBits(src[0]))
+		return appendBlockHeader(dst, header)
+	}
 
-	// Pack data in blocks continuously block after block.
-	offset := MetadataSize
-	for i := range totalBlocks {
-		var (
-			blockStart = i * blockSize
-			blockEnd   = min(blockStart+blockSize, len(forValues))
-			blockData  = forValues[blockStart:blockEnd]
-		)
-		// Pack this block.
-		bitpack.Pack(dst[offset:offset+blockSizeBytes], blockData, uint(bitWidth))
-		offset += blockSizeBytes
+	scale, cost := chooseScaling(src, candidates)
+	if cost >= min(len(src), SamplingSize)*valueSize[F]()*8 {
+		header.EncodingType = EncodingUncompressed
+		dst = appendBlockHeader(dst, header)
+		offset := len(dst)
+		dst = slices.Grow(dst, len(src)*valueSize[F]())[:offset+len(src)*valueSize[F]()]
+		for i, v := range src {
+			putFloat(dst[offset+i*valueSize[F]():], v)
+		}
+		return dst
 	}
 
+	forValues, exceptions := encodeToIntegers(e, src, scale)
+	minValue, maxValue := e.findBounds(forValues, exceptionSize[F]())
+	exceptions = e.applyFrameOfReference(forValues, exceptions, minValue, maxValue)
+	bitWidth := CalculateBitWidth(uint64(maxValue - minValue))
+
+	header.EncodingType = EncodingALP
+	header.Exponent = int8(scale.exponent)
+	header.Factor = int8(scale.factor)
+	header.BitWidth = uint8(bitWidth)
+	header.FrameOfRef = minValue
+	header.ExceptionCount = int32(len(exceptions))
+	dst = appendBlockHeader(dst, header)
+
+	var (
+		offset         = len(dst)
+		exceptionsSize = len(exceptions) * exceptionSize[F]()
+		packedSize     = bitpack.ByteCount(uint(len(forValues) * bitWidth))
+	)
+	dst = slices.Grow(dst, exceptionsSize+packedSize)[:offset+exceptionsSize+packedSize]
+	encodeExceptions(dst[offset:], src, exceptions)
+	bitpack.Pack(dst[offset+exceptionsSize:], forValues, uint(bitWidth))
 	return dst
 }
 
+// appendBlockHeader appends the header of a block to dst.
+func appendBlockHeader(dst []byte, header CompressionMetadata) []byte {
+	dst = append(dst, byte(header.EncodingType), byte(header.Exponent), byte(header.Factor), header.BitWidth)
+	dst = binary.LittleEndian.AppendUint32(dst, uint32(header.ExceptionCount))
+	return binary.LittleEndian.AppendUint64(dst, uint64(header.FrameOfRef))
+}
+
+// decodeBlockHeader decodes the header of a block with count values.
+func decodeBlockHeader(src []byte, count int) CompressionMetadata {
+	return CompressionMetadata{
+		EncodingType:   EncodingType(src[0]),
+		Count:          int32(count),
+		Exponent:       int8(src[1]),
+		Factor:         int8(src[2]),
+		BitWidth:       src[3],
+		ExceptionCount: int32(binary.LittleEndian.Uint32(src[4:8])),
+		FrameOfRef:     int64(binary.LittleEndian.Uint64(src[8:16])),
+	}
+}
+
+// StreamDecoder decodes float64 values encoded with StreamEncode.
 type StreamDecoder struct {
-	buf              []byte
+	streamDecoder[float64]
+}
+
+// streamDecoder decodes values of type F block by block.
+type streamDecoder[F Float] struct {
+	index            []byte // Byte offsets of the blocks
+	blocks           []byte // Encoded blocks
 	metadata         CompressionMetadata
 	blockSize        int
-	decodedBuf       []float64 // Buffer for decoded block
-	decodedBufOffset int       // Current read position in decoded buffer
-	valuesRead       int32     // Total values read so far
+	decodedBuf       []F   // Buffer for decoded block
+	decodedBufOffset int   // Current read position in decoded buffer
+	valuesRead       int32 // Total values read so far
+	err              error // Error found when validating the stream
 }
 
-func (d *StreamDecoder) Reset(buf []byte, blockSize int) {
-	d.buf = buf
-	d.blockSize = blockSize
-	if cap(d.decodedBuf) < blockSize {
-		d.decodedBuf = make([]float64, 0, blockSize)
-	}
+// Reset prepares the decoder to decode buf, using the block size recorded in
+// the stream. Errors found when validating the stream are returned by Decode
+// and Seek.
+func (d *streamDecoder[F]) Reset(buf []byte) {
+	d.index = nil
+	d.blocks = nil
+	d.blockSize = 0
 	d.decodedBuf = d.decodedBuf[:0]
 	d.decodedBufOffset = 0
 	d.valuesRead = 0
 
-	// Read global metadata
-	if len(buf) >= MetadataSize {
-		d.metadata = DecodeMetadata(buf)
-		d.buf = buf[MetadataSize:]
+	d.metadata = CompressionMetadata{}
+	d.err = nil
+	if len(buf) == 0 {
+		return
+	}
+
+	// Read and validate the metadata and all block headers.
+	metadata, blockSize, err := checkStream[F](buf)
+	if err != nil {
+		d.err = err
+		return
+	}
+	d.metadata = metadata
+	d.blockSize = blockSize
+
+	indexEnd := StreamHeaderSize + d.NumBlocks()*4
+	d.index = buf[StreamHeaderSize:indexEnd]
+	d.blocks = buf[indexEnd:]
+}
+
+// ResetBlockSize is like Reset but also checks that buf was encoded with the
+// given block size, so that Decode returns an error wrapping ErrBlockSize
+// instead of decoding a stream with unexpected blocks.
+func (d *streamDecoder[F]) ResetBlockSize(buf []byte, blockSize int) {
+	d.Reset(buf)
+	if d.err == nil && len(buf) > 0 && d.blockSize != blockSize {
+		d.err = fmt.Errorf("%w: stream has block size %d, expected %d", ErrBlockSize, d.blockSize, blockSize)
 	}
 }
 
-func (d *StreamDecoder) Decode(dst []float64) ([]float64, error) {
+// Decode decodes the next values into dst. It returns io.EOF along with the
+// last values, and an error wrapping ErrCorrupt if the stream is malformed.
+func (d *streamDecoder[F]) Decode(dst []F) ([]F, error) {
+	if d.err != nil {
+		return dst[:0], d.err
+	}
 	if d.valuesRead >= d.metadata.Count {
 		return dst[:0], io.EOF
 	}
 
 	// If we've consumed all values from current block, decode next block
 	if d.decodedBufOffset >= len(d.decodedBuf) {
-		// Determine block size
-		remaining := d.metadata.Count - d.valuesRead
-		blockSize := min(int32(d.blockSize), remaining)
-
-		// Calculate size of packed data for this block (no per-block padding)
-		packedSize := bitpack.ByteCount(uint(int(blockSize) * int(d.metadata.BitWidth)))
-
-		// Allocate buffer for decoded block
-		if cap(d.decodedBuf) < int(blockSize) {
-			d.decodedBuf = make([]float64, blockSize)
-		}
-		d.decodedBuf = d.decodedBuf[:blockSize]
-
-		// Unpack entire block
-		ints := unsafecast.Slice[int64](d.decodedBuf)
-		bitpack.Unpack(ints, d.buf, uint(d.metadata.BitWidth))
-		d.buf = d.buf[packedSize:]
-
-		// Convert to float64
-		minValue := d.metadata.FrameOfRef
-		invFactor := powersOf10[(10-d.metadata.Exponent+21)%21]
-
-		for i := range d.decodedBuf {
-			d.decodedBuf[i] = float64(ints[i]+minValue) * invFactor
-		}
-
-		d.decodedBufOffset = 0
+		d.decodeBlock(int(d.valuesRead) / d.blockSize)
 	}
 
 	// Return a chunk from the decoded buffer
@@ -157,3 +264,104 @@ func (d *StreamDecoder) Decode(dst []float64) ([]float64, error) {
 
 	return dst[:n], err
 }
+
+// decodeBlock decodes the block with the given index into the decoded buffer.
+func (d *streamDecoder[F]) decodeBlock(block int) {
+	// Determine block size
+	from := block * d.blockSize
+	blockSize := min(d.blockSize, int(d.metadata.Count)-from)
+
+	// Allocate buffer for decoded block
+	if cap(d.decodedBuf) < blockSize {
+		d.decodedBuf = make([]F, blockSize)
+	}
+	d.decodedBuf = d.decodedBuf[:blockSize]
+	d.decodedBufOffset = 0
+
+	data := d.blocks[binary.LittleEndian.Uint32(d.index[block*4:]):]
+	header := decodeBlockHeader(data, blockSize)
+	data = data[blockHeaderSize:]
+	switch header.EncodingType {
+	case EncodingConstant:
+		value := floatFromBits[F](uint64(header.FrameOfRef))
+		for i := range d.decodedBuf {
+			d.decodedBuf[i] = value
+		}
+	case EncodingUncompressed:
+		size := valueSize[F]()
+		for i := range d.decodedBuf {
+			d.decodedBuf[i] = readFloat[F](data[i*size:])
+		}
+	default:
+		// Unpack entire block, convert to floats and patch the exceptions
+		exceptionsSize := int(header.ExceptionCount) * exceptionSize[F]()
+		unpackFloats(d.decodedBuf, data[exceptionsSize:], 0, header)
+		patchExceptions(d.decodedBuf, data, int(header.ExceptionCount), 0)
+	}
+}
+
+// Seek positions the decoder so that the next call to Decode starts with the
+// value at index. The block holding the value is found in the block index, so
+// the decoder jumps directly to it and only decodes it if the index is not at
+// its start. Seeking backwards is supported, and seeking past the last value
+// positions the decoder at the end of the stream.
+func (d *streamDecoder[F]) Seek(index int) error {
+	if d.err != nil {
+		return d.err
+	}
+	if index < 0 {
+		return fmt.Errorf("negative seek index %d", index)
+	}
+	count := int(d.metadata.Count)
+	index = min(index, count)
+
+	// Keep the decoded block if it holds the value.
+	decodedFrom := int(d.valuesRead) - d.decodedBufOffset
+	if index >= decodedFrom && index < decodedFrom+len(d.decodedBuf) {
+		d.decodedBufOffset = index - decodedFrom
+		d.valuesRead = int32(index)
+		return nil
+	}
+
+	d.decodedBuf = d.decodedBuf[:0]
+	d.decodedBufOffset = 0
+	d.valuesRead = int32(index)
+	if index == count || index%d.blockSize == 0 {
+		return nil
+	}
+	d.decodeBlock(index / d.blockSize)
+	d.decodedBufOffset = index % d.blockSize
+	return nil
+}
+
+// Skip skips the next n values without decoding the blocks they are stored
+// in, see Seek.
+func (d *streamDecoder[F]) Skip(n int) error {
+	if n < 0 {
+		return fmt.Errorf("negative skip count %d", n)
+	}
+	return d.Seek(d.Position() + n)
+}
+
+// Position returns the index of the next value returned by Decode.
+func (d *streamDecoder[F]) Position() int {
+	return int(d.valuesRead)
+}
+
+// Len returns the number of values in the stream.
+func (d *streamDecoder[F]) Len() int {
+	return int(d.metadata.Count)
+}
+
+// BlockSize returns the block size recorded in the stream.
+func (d *streamDecoder[F]) BlockSize() int {
+	return d.blockSize
+}
+
+// NumBlocks returns the number of blocks in the stream.
+func (d *streamDecoder[F]) NumBlocks() int {
+	if d.metadata.Count == 0 {
+		return 0
+	}
+	return (int(d.metadata.Count) + d.blockSize - 1) / d.blockSize
+}
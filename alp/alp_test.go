@@ -1,12 +1,16 @@
 package alp
 
 import (
+	"encoding/binary"
+	"errors"
 	"io"
 	"math"
 	"math/rand"
+	"slices"
 	"testing"
 
 	"github.com/parquet-go/bitpack"
+	"github.com/parquet-go/bitpack/unsafecast"
 )
 
 // compareFloats compares two float64 values using relative error for large numbers
@@ -327,7 +331,7 @@ func TestDecodeRange(t *testing.T) {
 
 			// Decode with StreamDecoder
 			decoder := StreamDecoder{}
-			decoder.Reset(compressed, tt.blockSize)
+			decoder.Reset(compressed)
 
 			fullDecoded := make([]float64, 0, len(tt.data))
 			readBuf := make([]float64, tt.bufSize)
@@ -410,7 +414,7 @@ func TestStreamEncoderDecoder(t *testing.T) {
 
 			// Decode
 			decoder := StreamDecoder{}
-			decoder.Reset(compressed, tt.blockSize)
+			decoder.Reset(compressed)
 
 			decoded := make([]float64, 0, len(tt.data))
 			readBuf := make([]float64, tt.blockSize)
@@ -443,6 +447,293 @@ func TestStreamEncoderDecoder(t *testing.T) {
 	}
 }
 
+func TestStreamDecoderSeek(t *testing.T) {
+	const blockSize = 16
+	gen := rand.New(rand.NewSource(3))
+	data := make([]float64, 100)
+	for i := range data {
+		data[i] = math.Round(gen.NormFloat64()*10000) / 100
+	}
+	data[5], data[17], data[64], data[99] = math.NaN(), math.Inf(1), 1e300, math.Copysign(0, -1)
+	compressed := StreamEncode(nil, data, blockSize)
+
+	var decoder StreamDecoder
+	decoder.Reset(compressed)
+	if decoder.Len() != len(data) || decoder.NumBlocks() != 7 || decoder.Position() != 0 {
+		t.Fatalf("unexpected len %d, blocks %d, position %d", decoder.Len(), decoder.NumBlocks(), decoder.Position())
+	}
+
+	readBuf := make([]float64, 5)
+	expectValues := func(from int) {
+		t.Helper()
+		result, err := decoder.Decode(readBuf)
+		if err != nil && err != io.EOF {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := data[from:min(from+len(readBuf), len(data), from/blockSize*blockSize+blockSize)]
+		if !slices.EqualFunc(result, want, func(a, b float64) bool { return math.Float64bits(a) == math.Float64bits(b) }) {
+			t.Fatalf("values at %d: got %v, want %v", from, result, want)
+		}
+		if decoder.Position() != from+len(want) {
+			t.Fatalf("expected position %d, got %d", from+len(want), decoder.Position())
+		}
+	}
+
+	// Seek to block starts, into blocks, within the decoded block and backwards.
+	for _, index := range []int{0, 16, 17, 20, 3, 64, 66, 99, 50, 96, 32} {
+		if err := decoder.Seek(index); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if decoder.Position() != index {
+			t.Fatalf("expected position %d, got %d", index, decoder.Position())
+		}
+		expectValues(index)
+	}
+
+	// Skip within a block and across blocks.
+	if err := decoder.Seek(10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, n := range []int{0, 3, 20, 1, 40} {
+		from := decoder.Position() + n
+		if err := decoder.Skip(n); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expectValues(from)
+	}
+
+	// Seeking past the end positions the decoder at the end of the stream.
+	if err := decoder.Seek(len(data) + 10); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result, err := decoder.Decode(readBuf); len(result) != 0 || err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v, %v", result, err)
+	}
+	if err := decoder.Seek(-1); err == nil {
+		t.Fatal("expected error for negative index")
+	}
+	if err := decoder.Skip(-1); err == nil {
+		t.Fatal("expected error for negative count")
+	}
+
+	decoder.Reset(compressed[:len(compressed)-40])
+	if err := decoder.Seek(0); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+}
+
+func TestStreamHeader(t *testing.T) {
+	data := []float64{1.5, 2.25, 3.75, math.NaN(), 7.125}
+	for _, values := range [][]float64{data, nil} {
+		compressed := StreamEncode(nil, values, 2)
+		if metadata := DecodeMetadata(compressed); !metadata.Stream {
+			t.Fatalf("expected stream flag to be set")
+		}
+
+		var decoder StreamDecoder
+		decoder.Reset(compressed)
+		if decoder.BlockSize() != 2 {
+			t.Fatalf("expected block size 2, got %d", decoder.BlockSize())
+		}
+		decoded, err := decoder.Decode(make([]float64, 2))
+		if err != nil && err != io.EOF {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(decoded) != min(len(values), 2) {
+			t.Fatalf("expected %d values, got %d", min(len(values), 2), len(decoded))
+		}
+
+		decoder.ResetBlockSize(compressed, 2)
+		if _, err := decoder.Decode(make([]float64, 2)); err != nil && err != io.EOF {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		decoder.ResetBlockSize(compressed, 3)
+		if _, err := decoder.Decode(make([]float64, 2)); !errors.Is(err, ErrBlockSize) {
+			t.Fatalf("expected ErrBlockSize, got %v", err)
+		}
+
+		// Streams and blocks are not interchangeable.
+		if _, err := DecodeChecked(make([]float64, len(values)), compressed); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("expected ErrCorrupt, got %v", err)
+		}
+		decoder.Reset(Encode(nil, values))
+		if _, err := decoder.Decode(make([]float64, 2)); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("expected ErrCorrupt, got %v", err)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for block size 0")
+		}
+	}()
+	StreamEncode(nil, data, 0)
+}
+
+func TestStreamAdaptiveBlocks(t *testing.T) {
+	gen := rand.New(rand.NewSource(5))
+	data := make([]float64, 5*VectorSize)
+	for i := range data {
+		switch i / VectorSize {
+		case 0:
+			// A wide region which would inflate the bit-width of all values.
+			data[i] = math.Round(gen.Float64()*1e12) / 100
+		case 1:
+			data[i] = 42.5
+		case 2:
+			data[i] = math.Float64frombits(gen.Uint64())
+		default:
+			data[i] = math.Round(gen.Float64()*100) / 10
+		}
+	}
+
+	compressed := StreamEncode(nil, data, VectorSize)
+	if global := Encode(nil, data); len(compressed) >= len(global) {
+		t.Errorf("expected stream to be smaller than a single block, got %d and %d bytes", len(compressed), len(global))
+	}
+
+	var decoder StreamDecoder
+	decoder.Reset(compressed)
+	if decoder.NumBlocks() != 5 {
+		t.Fatalf("expected 5 blocks, got %d", decoder.NumBlocks())
+	}
+	decoded := make([]float64, 0, len(data))
+	buf := make([]float64, 1000)
+	for {
+		values, err := decoder.Decode(buf)
+		decoded = append(decoded, values...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if !slices.EqualFunc(decoded, data, func(a, b float64) bool { return math.Float64bits(a) == math.Float64bits(b) }) {
+		t.Fatal("decoded values do not match")
+	}
+
+	// Corrupt the header of the last block.
+	index := StreamHeaderSize + 4*4
+	offset := int(binary.LittleEndian.Uint32(compressed[index:]))
+	corrupted := slices.Clone(compressed)
+	corrupted[StreamHeaderSize+5*4+offset] = 42
+	decoder.Reset(corrupted)
+	if _, err := decoder.Decode(buf); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+}
+
+func TestStreamEncoder(t *testing.T) {
+	gen := rand.New(rand.NewSource(9))
+	data := make([]float64, 1000)
+	for i := range data {
+		data[i] = math.Round(gen.NormFloat64()*10000) / 100
+	}
+	data[10], data[500] = math.NaN(), 1e300
+
+	decodeStream := func(t *testing.T, stream []byte, blockSize int) []float64 {
+		t.Helper()
+		var decoder StreamDecoder
+		decoder.ResetBlockSize(stream, blockSize)
+		decoded := make([]float64, 0, decoder.Len())
+		buf := make([]float64, 100)
+		for {
+			values, err := decoder.Decode(buf)
+			decoded = append(decoded, values...)
+			if err == io.EOF {
+				return decoded
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+	bitsEqual := func(a, b float64) bool { return math.Float64bits(a) == math.Float64bits(b) }
+
+	encoder := NewStreamEncoder(64)
+	if header, blocks := encoder.Finish(); len(decodeStream(t, slices.Concat(header, blocks), 64)) != 0 {
+		t.Fatal("expected empty stream")
+	}
+
+	// Append values one at a time and in batches, flushing in between.
+	var flushed []byte
+	for i, v := range data[:300] {
+		encoder.Append(v)
+		if i%77 == 0 {
+			blocks := encoder.Flush()
+			if i < 63 && len(blocks) != 0 {
+				t.Fatalf("unexpected blocks after %d values", i+1)
+			}
+			flushed = append(flushed, blocks...)
+		}
+	}
+	encoder.Write(data[300:301])
+	encoder.Write(data[301:900])
+	flushed = append(flushed, encoder.Flush()...)
+	if blocks := encoder.Flush(); len(blocks) != 0 {
+		t.Fatalf("expected no blocks after flushing, got %d bytes", len(blocks))
+	}
+	encoder.Write(data[900:])
+	if encoder.Len() != len(data) {
+		t.Fatalf("expected %d values, got %d", len(data), encoder.Len())
+	}
+	header, blocks := encoder.Finish()
+	stream := slices.Concat(header, flushed, blocks)
+	if decoded := decodeStream(t, stream, 64); !slices.EqualFunc(decoded, data, bitsEqual) {
+		t.Fatal("values do not match")
+	}
+
+	// Finish starts a new stream, and Reset reuses the encoder.
+	if encoder.Len() != 0 {
+		t.Fatalf("expected no values after finishing, got %d", encoder.Len())
+	}
+	encoder.Write(data[:10])
+	encoder.Reset(make([]byte, 0, 4096))
+	encoder.Write(data[:130])
+	header, blocks = encoder.Finish()
+	if decoded := decodeStream(t, slices.Concat(header, blocks), 64); !slices.EqualFunc(decoded, data[:130], bitsEqual) {
+		t.Fatal("values do not match after reset")
+	}
+
+	t.Run("zero value", func(t *testing.T) {
+		var encoder StreamEncoder
+		encoder.Reset(nil)
+		encoder.Append(1)
+		encoder.Write(data)
+		flushed := encoder.Flush()
+		header, blocks := encoder.Finish()
+		want := append([]float64{1}, data...)
+		if decoded := decodeStream(t, slices.Concat(header, flushed, blocks), DefaultBlockSize); !slices.EqualFunc(decoded, want, bitsEqual) {
+			t.Fatal("values do not match")
+		}
+	})
+
+	t.Run("float32", func(t *testing.T) {
+		encoder := NewStreamEncoderFloat32(16)
+		values := []float32{1.5, 2.25, float32(math.Inf(1)), 3.75}
+		for range 10 {
+			encoder.Write(values)
+		}
+		header, blocks := encoder.Finish()
+		var decoder StreamDecoderFloat32
+		decoder.Reset(slices.Concat(header, blocks))
+		decoded, err := decoder.Decode(make([]float32, 40))
+		if err != nil && err != io.EOF {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(decoded) != 16 || decoded[2] != float32(math.Inf(1)) || decoded[15] != 3.75 {
+			t.Fatalf("unexpected values %v", decoded)
+		}
+		if err := decoder.Seek(39); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if decoded, _ := decoder.Decode(make([]float32, 4)); len(decoded) != 1 || decoded[0] != 3.75 {
+			t.Fatalf("unexpected values %v", decoded)
+		}
+	})
+}
+
 func FuzzStreamEncodeDecode(f *testing.F) {
 	// Add seed corpus with various sizes and block sizes
 	f.Add(uint8(10), uint8(5), int64(42))
@@ -475,7 +766,7 @@ func FuzzStreamEncodeDecode(f *testing.F) {
 
 		// Decode with StreamDecoder
 		decoder := StreamDecoder{}
-		decoder.Reset(compressed, int(blockSize))
+		decoder.Reset(compressed)
 
 		decoded := make([]float64, 0, len(src))
 		readBuf := make([]float64, blockSize)
@@ -506,3 +797,947 @@ func FuzzStreamEncodeDecode(f *testing.F) {
 		}
 	})
 }
+
+func TestExceptions(t *testing.T) {
+	sensor := func() []float64 {
+		data := make([]float64, 120)
+		for i := range data {
+			data[i] = 20 + float64(i%50)*0.1
+		}
+		return data
+	}
+	tests := []struct {
+		name           string
+		data           []float64
+		wantExceptions int32
+		maxBitWidth    uint8
+	}{
+		{
+			name:           "no outliers",
+			data:           sensor(),
+			wantExceptions: 0,
+			maxBitWidth:    9,
+		},
+		{
+			name: "unscalable values",
+			data: func() []float64 {
+				data := sensor()
+				data[7] = math.Pi
+				data[100] = math.E
+				return data
+			}(),
+			wantExceptions: 2,
+			maxBitWidth:    9,
+		},
+		{
+			name: "spikes",
+			data: func() []float64 {
+				data := sensor()
+				data[3] = 1e9
+				data[60] = -5e8
+				return data
+			}(),
+			wantExceptions: 2,
+			maxBitWidth:    9,
+		},
+		{
+			name: "leading exception",
+			data: func() []float64 {
+				data := sensor()
+				data[0] = math.Pi
+				return data
+			}(),
+			wantExceptions: 1,
+			maxBitWidth:    9,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed := Encode(nil, tt.data)
+			metadata := DecodeMetadata(compressed)
+			if metadata.ExceptionCount != tt.wantExceptions {
+				t.Errorf("exception count: got %d, want %d", metadata.ExceptionCount, tt.wantExceptions)
+			}
+			if metadata.BitWidth > tt.maxBitWidth {
+				t.Errorf("bit width: got %d, want at most %d", metadata.BitWidth, tt.maxBitWidth)
+			}
+
+			decoded := Decode(make([]float64, len(tt.data)), compressed)
+			for i := range tt.data {
+				equal, relErr, absErr := compareFloats(decoded[i], tt.data[i])
+				if !equal {
+					t.Errorf("value mismatch at index %d: got %f, want %f (abs err: %e, rel err: %e)",
+						i, decoded[i], tt.data[i], absErr, relErr)
+				}
+			}
+
+			// The stream format shares the exceptions with the block format.
+			const blockSize = 16
+			decoder := StreamDecoder{}
+			decoder.Reset(StreamEncode(nil, tt.data, blockSize))
+			streamed := make([]float64, 0, len(tt.data))
+			readBuf := make([]float64, 10)
+			for {
+				result, err := decoder.Decode(readBuf)
+				streamed = append(streamed, result...)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			for i := range tt.data {
+				equal, _, _ := compareFloats(streamed[i], tt.data[i])
+				if !equal {
+					t.Errorf("stream value mismatch at index %d: got %f, want %f", i, streamed[i], tt.data[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRealDoubles(t *testing.T) {
+	gen := rand.New(rand.NewSource(42))
+	tests := []struct {
+		name     string
+		data     []float64
+		encoding EncodingType
+	}{
+		{
+			name: "large doubles",
+			data: func() []float64 {
+				data := make([]float64, 1000)
+				for i := range data {
+					data[i] = 1e300 * (1 + gen.Float64())
+				}
+				return data
+			}(),
+			encoding: EncodingRD,
+		},
+		{
+			name: "small doubles with outliers",
+			data: func() []float64 {
+				data := make([]float64, 1000)
+				for i := range data {
+					data[i] = 1e-300 * (1 + gen.Float64())
+				}
+				data[10] = -1e300
+				data[500] = 1
+				return data
+			}(),
+			encoding: EncodingRD,
+		},
+		{
+			name: "random bits",
+			data: func() []float64 {
+				data := make([]float64, 100)
+				for i := range data {
+					data[i] = math.Float64frombits(gen.Uint64())
+				}
+				return data
+			}(),
+			encoding: EncodingUncompressed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed := Encode(nil, tt.data)
+			metadata := DecodeMetadata(compressed)
+			if metadata.EncodingType != tt.encoding {
+				t.Fatalf("encoding: got %d, want %d", metadata.EncodingType, tt.encoding)
+			}
+			if tt.encoding == EncodingRD && len(compressed) >= len(tt.data)*8 {
+				t.Errorf("expected compression, got %d bytes for %d values", len(compressed), len(tt.data))
+			}
+
+			decoded := Decode(make([]float64, len(tt.data)), compressed)
+			for i := range tt.data {
+				if math.Float64bits(decoded[i]) != math.Float64bits(tt.data[i]) {
+					t.Errorf("value mismatch at index %d: got %v, want %v", i, decoded[i], tt.data[i])
+				}
+			}
+
+			t.Logf("Compression ratio: %.2f%%", CompressionRatio(len(tt.data), len(compressed))*100)
+		})
+	}
+}
+
+func TestBitExact(t *testing.T) {
+	nanPayload := math.Float64frombits(0x7ff8_0000_dead_beef)
+	tests := []struct {
+		name string
+		data []float64
+	}{
+		{
+			name: "negative zero",
+			data: []float64{0, math.Copysign(0, -1), 1.5, 2.5},
+		},
+		{
+			name: "constant negative zero",
+			data: []float64{math.Copysign(0, -1), math.Copysign(0, -1)},
+		},
+		{
+			name: "nan payloads",
+			data: []float64{1.1, nanPayload, math.NaN(), 2.2, 3.3},
+		},
+		{
+			name: "constant nan",
+			data: []float64{nanPayload, nanPayload, nanPayload},
+		},
+		{
+			name: "infinities",
+			data: []float64{math.Inf(1), 1.25, math.Inf(-1), 2.5},
+		},
+		{
+			name: "outside int64 range",
+			data: []float64{1e19, -1e19, math.MaxFloat64, 0.5, 0.25},
+		},
+		{
+			name: "subnormals",
+			data: []float64{math.SmallestNonzeroFloat64, 4.9e-320, 1.0, 2.0},
+		},
+		{
+			name: "last bit differences",
+			data: []float64{0.1, math.Nextafter(0.1, 1), 0.2, math.Nextafter(0.3, 0)},
+		},
+		{
+			name: "decimals",
+			data: []float64{0.3, 20.3, 1.7, 12.34, 99.99, 0.01, 1234.5678},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed := Encode(nil, tt.data)
+			decoded := Decode(make([]float64, len(tt.data)), compressed)
+			for i := range tt.data {
+				if math.Float64bits(decoded[i]) != math.Float64bits(tt.data[i]) {
+					t.Errorf("bits mismatch at index %d: got %x, want %x",
+						i, math.Float64bits(decoded[i]), math.Float64bits(tt.data[i]))
+				}
+			}
+		})
+	}
+}
+
+func FuzzEncodeDecode(f *testing.F) {
+	f.Add(uint8(10), int64(42), uint8(0))
+	f.Add(uint8(120), int64(123), uint8(2))
+	f.Add(uint8(255), int64(456), uint8(4))
+	f.Add(uint8(1), int64(0), uint8(1))
+
+	f.Fuzz(func(t *testing.T, size uint8, seed int64, decimals uint8) {
+		gen := rand.New(rand.NewSource(seed))
+		scale := math.Pow10(int(decimals % 16))
+		src := make([]float64, size)
+		for i := range src {
+			switch gen.Intn(16) {
+			case 0:
+				src[i] = math.Float64frombits(gen.Uint64())
+			case 1:
+				src[i] = math.Copysign(0, -1)
+			default:
+				src[i] = math.Round(gen.NormFloat64()*1000*scale) / scale
+			}
+		}
+
+		decoded := Decode(make([]float64, len(src)), Encode(nil, src))
+		for i := range src {
+			if math.Float64bits(decoded[i]) != math.Float64bits(src[i]) {
+				t.Fatalf("bits mismatch at index %d: got %x, want %x",
+					i, math.Float64bits(decoded[i]), math.Float64bits(src[i]))
+			}
+		}
+	})
+}
+
+func TestScaling(t *testing.T) {
+	repeat := func(values ...float64) []float64 {
+		data := make([]float64, 0, 1000)
+		for len(data) < cap(data) {
+			data = append(data, values...)
+		}
+		return data
+	}
+	tests := []struct {
+		name        string
+		data        []float64
+		maxBitWidth uint8
+	}{
+		{
+			name:        "two decimals",
+			data:        repeat(12.50, 13.00, 14.50, 12.75, 13.25),
+			maxBitWidth: 8,
+		},
+		{
+			name:        "one decimal",
+			data:        repeat(20.1, 20.3, 20.7, 21.9, 22.2, 20.0),
+			maxBitWidth: 5,
+		},
+		{
+			name:        "trailing zeros",
+			data:        repeat(3e6, 4e6, 7e6, 1.2e7),
+			maxBitWidth: 4,
+		},
+		{
+			name: "sampled vectors with different precision",
+			data: func() []float64 {
+				data := make([]float64, 10*VectorSize)
+				for i := range data {
+					data[i] = float64(i%100) / 100
+					if i >= 5*VectorSize {
+						data[i] = float64(i % 100)
+					}
+				}
+				return data
+			}(),
+			maxBitWidth: 14,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed := Encode(nil, tt.data)
+			metadata := DecodeMetadata(compressed)
+			if metadata.EncodingType != EncodingALP {
+				t.Fatalf("encoding: got %d, want %d", metadata.EncodingType, EncodingALP)
+			}
+			if metadata.ExceptionCount != 0 {
+				t.Errorf("exception count: got %d, want 0", metadata.ExceptionCount)
+			}
+			if metadata.BitWidth > tt.maxBitWidth {
+				t.Errorf("bit width: got %d, want at most %d (exponent %d, factor %d)",
+					metadata.BitWidth, tt.maxBitWidth, metadata.Exponent, metadata.Factor)
+			}
+
+			decoded := Decode(make([]float64, len(tt.data)), compressed)
+			for i := range tt.data {
+				if math.Float64bits(decoded[i]) != math.Float64bits(tt.data[i]) {
+					t.Fatalf("value mismatch at index %d: got %v, want %v", i, decoded[i], tt.data[i])
+				}
+			}
+		})
+	}
+}
+
+func TestScalingTies(t *testing.T) {
+	generate := func(n int, value func(i int) float64) []float64 {
+		data := make([]float64, n)
+		for i := range data {
+			data[i] = value(i)
+		}
+		return data
+	}
+	tests := []struct {
+		name         string
+		data         []float64
+		wantExponent int8
+		wantFactor   int8
+	}{
+		{
+			name:         "few integers",
+			data:         generate(10, func(i int) float64 { return float64(100 + i*7%20) }),
+			wantExponent: 0,
+			wantFactor:   0,
+		},
+		{
+			name:         "binary fractions",
+			data:         generate(120, func(i int) float64 { return []float64{12.5, 13, 14.5, 12.75, 13.25}[i%5] }),
+			wantExponent: 2,
+			wantFactor:   0,
+		},
+		{
+			name:         "integers across vectors",
+			data:         generate(4*VectorSize, func(i int) float64 { return float64(i % 1000) }),
+			wantExponent: 0,
+			wantFactor:   0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := DecodeMetadata(Encode(nil, tt.data))
+			if metadata.Exponent != tt.wantExponent || metadata.Factor != tt.wantFactor {
+				t.Errorf("scaling: got exponent %d and factor %d, want %d and %d",
+					metadata.Exponent, metadata.Factor, tt.wantExponent, tt.wantFactor)
+			}
+			if metadata.ExceptionCount != 0 {
+				t.Errorf("exception count: got %d, want 0", metadata.ExceptionCount)
+			}
+		})
+	}
+}
+
+func TestFloat32(t *testing.T) {
+	sensor := make([]float32, 2000)
+	gen := rand.New(rand.NewSource(7))
+	for i := range sensor {
+		sensor[i] = float32(math.Round(20+gen.NormFloat64()*500)) / 100
+	}
+	randomBits := make([]float32, 500)
+	for i := range randomBits {
+		randomBits[i] = math.Float32frombits(gen.Uint32())
+	}
+
+	tests := []struct {
+		name     string
+		data     []float32
+		encoding EncodingType
+	}{
+		{
+			name:     "empty",
+			data:     []float32{},
+			encoding: EncodingNone,
+		},
+		{
+			name:     "constant nan",
+			data:     []float32{math.Float32frombits(0x7f80_0001), math.Float32frombits(0x7f80_0001)},
+			encoding: EncodingConstant,
+		},
+		{
+			name:     "decimals",
+			data:     sensor,
+			encoding: EncodingALP,
+		},
+		{
+			name: "special values",
+			data: append(slices.Repeat([]float32{1.5, 2.25, 0.1, 7.75}, 10),
+				float32(math.Inf(1)), float32(math.NaN()), float32(math.Copysign(0, -1)), 3e38),
+			encoding: EncodingALP,
+		},
+		{
+			name:     "random bits",
+			data:     randomBits,
+			encoding: EncodingUncompressed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed := EncodeFloat32(nil, tt.data)
+			metadata := DecodeMetadata(compressed)
+			if metadata.EncodingType != tt.encoding {
+				t.Fatalf("expected encoding %d, got %d", tt.encoding, metadata.EncodingType)
+			}
+			if metadata.ValueType != ValueTypeFloat32 {
+				t.Fatalf("expected float32 value type, got %d", metadata.ValueType)
+			}
+
+			decoded := DecodeFloat32(make([]float32, len(tt.data)), compressed)
+			if len(decoded) != len(tt.data) {
+				t.Fatalf("expected %d values, got %d", len(tt.data), len(decoded))
+			}
+			for i := range tt.data {
+				if math.Float32bits(decoded[i]) != math.Float32bits(tt.data[i]) {
+					t.Fatalf("bits mismatch at index %d: got %x, want %x",
+						i, math.Float32bits(decoded[i]), math.Float32bits(tt.data[i]))
+				}
+			}
+
+			if len(tt.data) > 0 {
+				if got := Decode(make([]float64, len(tt.data)), compressed); len(got) != 0 {
+					t.Errorf("expected float32 data not to decode as float64, got %d values", len(got))
+				}
+			}
+		})
+	}
+
+	t.Run("compression", func(t *testing.T) {
+		compressed := EncodeFloat32(nil, sensor)
+		if metadata := DecodeMetadata(compressed); metadata.ExceptionCount > int32(len(sensor)/100) {
+			t.Errorf("expected at most 1%% exceptions, got %d", metadata.ExceptionCount)
+		}
+		if len(compressed) >= len(sensor)*2 {
+			t.Errorf("expected less than 16 bits per value, got %d bytes", len(compressed))
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		data := append(slices.Clone(sensor), float32(math.NaN()), 1e30)
+		compressed := StreamEncodeFloat32(nil, data, 128)
+
+		var (
+			decoder StreamDecoderFloat32
+			decoded []float32
+			buf     = make([]float32, 100)
+		)
+		decoder.Reset(compressed)
+		for {
+			values, err := decoder.Decode(buf)
+			decoded = append(decoded, values...)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(decoded) != len(data) {
+			t.Fatalf("expected %d values, got %d", len(data), len(decoded))
+		}
+		for i := range data {
+			if math.Float32bits(decoded[i]) != math.Float32bits(data[i]) {
+				t.Fatalf("bits mismatch at index %d: got %x, want %x",
+					i, math.Float32bits(decoded[i]), math.Float32bits(data[i]))
+			}
+		}
+
+		var float64Decoder StreamDecoder
+		float64Decoder.Reset(compressed)
+		if _, err := float64Decoder.Decode(make([]float64, 10)); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("expected float32 stream not to decode as float64, got %v", err)
+		}
+	})
+}
+
+func FuzzEncodeDecodeFloat32(f *testing.F) {
+	f.Add(uint8(10), int64(42), uint8(0))
+	f.Add(uint8(200), int64(123), uint8(2))
+	f.Add(uint8(1), int64(0), uint8(1))
+
+	f.Fuzz(func(t *testing.T, size uint8, seed int64, decimals uint8) {
+		gen := rand.New(rand.NewSource(seed))
+		scale := math.Pow10(int(decimals % 8))
+		src := make([]float32, size)
+		for i := range src {
+			switch gen.Intn(16) {
+			case 0:
+				src[i] = math.Float32frombits(gen.Uint32())
+			case 1:
+				src[i] = float32(math.Copysign(0, -1))
+			default:
+				src[i] = float32(math.Round(gen.NormFloat64()*1000*scale) / scale)
+			}
+		}
+
+		decoded := DecodeFloat32(make([]float32, len(src)), EncodeFloat32(nil, src))
+		for i := range src {
+			if math.Float32bits(decoded[i]) != math.Float32bits(src[i]) {
+				t.Fatalf("bits mismatch at index %d: got %x, want %x",
+					i, math.Float32bits(decoded[i]), math.Float32bits(src[i]))
+			}
+		}
+	})
+}
+
+func TestDecodeChecked(t *testing.T) {
+	var (
+		decimals = []float64{1.5, 2.25, 3.75, 100.5, math.NaN(), 7.125}
+		rd       = []float64{1e300, 1.1e-300, math.Pi, math.E}
+		random   = []float64{math.Float64frombits(0x1234_5678_9abc_def0), math.Float64frombits(0xfedc_ba98_7654_3210)}
+	)
+	corrupt := func(data []byte, f func([]byte)) []byte {
+		data = slices.Clone(data)
+		f(data)
+		return data
+	}
+	alp := Encode(nil, decimals)
+
+	tests := []struct {
+		name string
+		data []byte
+		dst  []float64
+		err  error
+	}{
+		{name: "alp", data: alp, dst: make([]float64, 6)},
+		{name: "rd", data: Encode(nil, rd), dst: make([]float64, 4)},
+		{name: "uncompressed", data: Encode(nil, random), dst: make([]float64, 2)},
+		{name: "constant", data: Encode(nil, []float64{1, 1}), dst: make([]float64, 2)},
+		{name: "empty", data: Encode(nil, nil), dst: nil},
+		{name: "no data", data: nil, dst: make([]float64, 6), err: ErrCorrupt},
+		{name: "short metadata", data: alp[:MetadataSize-1], dst: make([]float64, 6), err: ErrCorrupt},
+		{name: "truncated", data: alp[:len(alp)-20], dst: make([]float64, 6), err: ErrCorrupt},
+		{name: "truncated rd", data: Encode(nil, rd)[:MetadataSize+10], dst: make([]float64, 4), err: ErrCorrupt},
+		{name: "truncated uncompressed", data: Encode(nil, random)[:MetadataSize+8], dst: make([]float64, 2), err: ErrCorrupt},
+		{name: "short dst", data: alp, dst: make([]float64, 5), err: ErrShortBuffer},
+		{name: "float32 data", data: EncodeFloat32(nil, []float32{1.5, 2.5}), dst: make([]float64, 2), err: ErrCorrupt},
+		{
+			name: "unknown encoding",
+			data: corrupt(alp, func(b []byte) { b[0] = 42 }),
+			dst:  make([]float64, 6),
+			err:  ErrInvalidEncoding,
+		},
+		{
+			name: "bit width",
+			data: corrupt(alp, func(b []byte) { b[6] = 65 }),
+			dst:  make([]float64, 6),
+			err:  ErrCorrupt,
+		},
+		{
+			name: "exponent",
+			data: corrupt(alp, func(b []byte) { b[5] = MaxExponent + 1 }),
+			dst:  make([]float64, 6),
+			err:  ErrCorrupt,
+		},
+		{
+			name: "exception count",
+			data: corrupt(alp, func(b []byte) { b[23] = 7 }),
+			dst:  make([]float64, 6),
+			err:  ErrCorrupt,
+		},
+		{
+			name: "exception position",
+			data: corrupt(alp, func(b []byte) { b[MetadataSize] = 6 }),
+			dst:  make([]float64, 6),
+			err:  ErrCorrupt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := DecodeChecked(tt.dst, tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+			want := Decode(make([]float64, len(tt.dst)), tt.data)
+			if len(decoded) != len(want) {
+				t.Fatalf("expected %d values, got %d", len(want), len(decoded))
+			}
+			for i := range want {
+				if math.Float64bits(decoded[i]) != math.Float64bits(want[i]) {
+					t.Fatalf("bits mismatch at index %d", i)
+				}
+			}
+		})
+	}
+}
+
+func FuzzDecodeChecked(f *testing.F) {
+	f.Add(Encode(nil, []float64{1.5, 2.25, 3.75, 100.5, math.NaN(), 7.125}))
+	f.Add(Encode(nil, []float64{1e300, 1.1e-300, math.Pi, math.E}))
+	f.Add(Encode(nil, []float64{math.Float64frombits(0x1234_5678_9abc_def0)}))
+	f.Add(EncodeFloat32(nil, []float32{1.5, 2.25, float32(math.Inf(1))}))
+	f.Add(StreamEncode(nil, []float64{1.5, 2.25, 3.75, math.NaN(), 7.125}, 2))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = DecodeChecked(make([]float64, 64), data)
+		_, _ = DecodeFloat32Checked(make([]float32, 64), data)
+
+		var decoder StreamDecoder
+		decoder.Reset(data)
+		buf := make([]float64, 3)
+		for range 100 {
+			if _, err := decoder.Decode(buf); err != nil {
+				break
+			}
+		}
+	})
+}
+
+func TestChecksum(t *testing.T) {
+	data := []float64{1.5, 2.25, 3.75, 100.5, math.NaN(), 7.125}
+	encoded := AppendChecksum(Encode(nil, data))
+	if !DecodeMetadata(encoded).Checksum {
+		t.Fatal("expected checksum flag to be set")
+	}
+
+	for name, decode := range map[string]func([]byte) ([]float64, error){
+		"checked": func(b []byte) ([]float64, error) {
+			return DecodeChecked(make([]float64, len(data)), b)
+		},
+		"unchecked": func(b []byte) ([]float64, error) {
+			return Decode(make([]float64, len(data)), b), nil
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			decoded, err := decode(encoded)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for i := range data {
+				if math.Float64bits(decoded[i]) != math.Float64bits(data[i]) {
+					t.Fatalf("bits mismatch at index %d", i)
+				}
+			}
+		})
+	}
+
+	t.Run("bit flip", func(t *testing.T) {
+		for _, pos := range []int{1, MetadataSize, len(encoded) - ChecksumSize - 1, len(encoded) - 1} {
+			corrupted := slices.Clone(encoded)
+			corrupted[pos] ^= 0x10
+			if _, err := DecodeChecked(make([]float64, 64), corrupted); !errors.Is(err, ErrChecksum) {
+				t.Errorf("expected ErrChecksum for flipped byte %d, got %v", pos, err)
+			}
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		encoded := AppendChecksum(StreamEncode(nil, data, 4))
+
+		var decoder StreamDecoder
+		decoder.Reset(encoded)
+		decoded := make([]float64, 0, len(data))
+		buf := make([]float64, 4)
+		for {
+			values, err := decoder.Decode(buf)
+			decoded = append(decoded, values...)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+		if len(decoded) != len(data) {
+			t.Fatalf("expected %d values, got %d", len(data), len(decoded))
+		}
+
+		encoded[len(encoded)-ChecksumSize-1] ^= 1
+		decoder.Reset(encoded)
+		if _, err := decoder.Decode(buf); !errors.Is(err, ErrChecksum) {
+			t.Fatalf("expected ErrChecksum, got %v", err)
+		}
+	})
+}
+
+func TestRandomAccess(t *testing.T) {
+	gen := rand.New(rand.NewSource(11))
+	decimals := make([]float64, 1000)
+	for i := range decimals {
+		decimals[i] = math.Round(gen.NormFloat64()*10000) / 100
+	}
+	decimals[3], decimals[500], decimals[999] = math.NaN(), math.Inf(-1), math.Copysign(0, -1)
+	// Many exceptions, including the first and the last value.
+	spikes := slices.Clone(decimals)
+	for i := 0; i < len(spikes); i += 20 {
+		spikes[i] = math.Pi * float64(i+1)
+	}
+	spikes[999] = math.E
+	doubles := make([]float64, 1000)
+	for i := range doubles {
+		doubles[i] = 1e-300 * (1 + gen.Float64())
+	}
+	doubles[10], doubles[777] = -1e300, 1
+	randomBits := make([]float64, 100)
+	for i := range randomBits {
+		randomBits[i] = math.Float64frombits(gen.Uint64())
+	}
+	float32s := make([]float32, 1000)
+	for i := range float32s {
+		float32s[i] = float32(math.Round(gen.NormFloat64()*10000)) / 100
+	}
+	float32s[42] = float32(math.NaN())
+
+	tests := []struct {
+		name     string
+		data     []byte
+		encoding EncodingType
+	}{
+		{name: "empty", data: Encode(nil, nil), encoding: EncodingNone},
+		{name: "constant", data: Encode(nil, []float64{1.5, 1.5, 1.5}), encoding: EncodingConstant},
+		{name: "decimals", data: Encode(nil, decimals), encoding: EncodingALP},
+		{name: "many exceptions", data: Encode(nil, spikes), encoding: EncodingALP},
+		{name: "doubles", data: Encode(nil, doubles), encoding: EncodingRD},
+		{name: "random bits", data: Encode(nil, randomBits), encoding: EncodingUncompressed},
+		{name: "float32", data: EncodeFloat32(nil, float32s), encoding: EncodingALP},
+		{name: "float32 constant", data: EncodeFloat32(nil, []float32{2.5, 2.5}), encoding: EncodingConstant},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := DecodeMetadata(tt.data)
+			if metadata.EncodingType != tt.encoding {
+				t.Fatalf("expected encoding %d, got %d", tt.encoding, metadata.EncodingType)
+			}
+			if metadata.ValueType == ValueTypeFloat32 {
+				testRandomAccess(t, tt.data, DecodeFloat32, AtFloat32, DecodeRangeFloat32)
+				return
+			}
+			testRandomAccess(t, tt.data, Decode, At, DecodeRange)
+		})
+	}
+}
+
+func testRandomAccess[F Float](
+	t *testing.T,
+	data []byte,
+	decodeFunc func([]F, []byte) []F,
+	atFunc func([]byte, int) F,
+	decodeRangeFunc func([]F, []byte, int, int) []F,
+) {
+	t.Helper()
+
+	count := int(DecodeMetadata(data).Count)
+	want := decodeFunc(make([]F, count), data)
+	for i := range want {
+		if got := atFunc(data, i); floatBits(got) != floatBits(want[i]) {
+			t.Fatalf("At(%d): got %v, want %v", i, got, want[i])
+		}
+	}
+
+	bounds := []int{0, 1, 7, 8, 9, 63, 64, 500, 1023, 1024, 1025}
+	for _, start := range append(bounds, count) {
+		for _, end := range append(bounds, count) {
+			if start > end || end > count {
+				continue
+			}
+			got := decodeRangeFunc(nil, data, start, end)
+			if !slices.EqualFunc(got, want[start:end], func(a, b F) bool { return floatBits(a) == floatBits(b) }) {
+				t.Fatalf("DecodeRange(%d, %d): got %v, want %v", start, end, got, want[start:end])
+			}
+		}
+	}
+
+	for _, f := range []func(){
+		func() { atFunc(data, -1) },
+		func() { atFunc(data, count) },
+		func() { decodeRangeFunc(nil, data, 1, 0) },
+		func() { decodeRangeFunc(nil, data, 0, count+1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected panic for out of range index")
+				}
+			}()
+			f()
+		}()
+	}
+}
+
+func FuzzRandomAccess(f *testing.F) {
+	f.Add(uint16(100), int64(42), uint8(2), uint16(10), uint16(50))
+	f.Add(uint16(1000), int64(7), uint8(0), uint16(3), uint16(999))
+
+	f.Fuzz(func(t *testing.T, size uint16, seed int64, decimals uint8, start, end uint16) {
+		gen := rand.New(rand.NewSource(seed))
+		scale := math.Pow10(int(decimals % 16))
+		src := make([]float64, size%4096)
+		for i := range src {
+			switch gen.Intn(16) {
+			case 0:
+				src[i] = math.Float64frombits(gen.Uint64())
+			case 1:
+				src[i] = 1e300 * gen.Float64()
+			default:
+				src[i] = math.Round(gen.NormFloat64()*1000*scale) / scale
+			}
+		}
+		lo, hi := min(int(start), int(end), len(src)), min(max(int(start), int(end)), len(src))
+
+		data := Encode(nil, src)
+		got := DecodeRange(nil, data, lo, hi)
+		for i, v := range got {
+			if math.Float64bits(v) != math.Float64bits(src[lo+i]) {
+				t.Fatalf("DecodeRange(%d, %d) mismatch at index %d: got %v, want %v", lo, hi, lo+i, v, src[lo+i])
+			}
+		}
+		if lo < len(src) {
+			if v := At(data, lo); math.Float64bits(v) != math.Float64bits(src[lo]) {
+				t.Fatalf("At(%d): got %v, want %v", lo, v, src[lo])
+			}
+		}
+	})
+}
+
+func TestEncoder(t *testing.T) {
+	gen := rand.New(rand.NewSource(42))
+	var (
+		prices  = make([]float64, 1024)
+		doubles = make([]float64, 1024)
+		floats  = make([]float32, 1024)
+	)
+	for i := range prices {
+		prices[i] = float64(gen.Intn(100000)) / 100
+		doubles[i] = gen.Float64()
+		floats[i] = float32(gen.Intn(10000)) / 8
+	}
+	// Spikes are stored as exceptions.
+	prices[10], prices[500] = 1e12, math.Pi
+
+	datasets := [][]float64{prices, doubles, prices[:5], make([]float64, 10), nil}
+	var (
+		e   Encoder
+		dst []byte
+	)
+	for _, src := range datasets {
+		dst = e.Encode(dst[:0], src)
+		if want := Encode(nil, src); !slices.Equal(dst, want) {
+			t.Fatalf("encoder output differs from Encode for %d values", len(src))
+		}
+		if decoded := Decode(make([]float64, len(src)), dst); !slices.Equal(decoded, src) {
+			t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded, src)
+		}
+	}
+	dst = e.EncodeFloat32(dst[:0], floats)
+	if decoded := DecodeFloat32(make([]float32, len(floats)), dst); !slices.Equal(decoded, floats) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded, floats)
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		for _, src := range datasets {
+			dst = e.Encode(dst[:0], src)
+		}
+		dst = e.EncodeFloat32(dst[:0], floats)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+}
+
+func TestDecodeIntegers(t *testing.T) {
+	testDecodeIntegers(t)
+}
+
+// testDecodeIntegers checks that decodeIntegers and decodeIntegersFloat32,
+// which may use SIMD instructions, match the generic implementations bit for
+// bit.
+func testDecodeIntegers(t *testing.T) {
+	gen := rand.New(rand.NewSource(13))
+	for bitWidth := uint(0); bitWidth <= 64; bitWidth++ {
+		mask := uint64(1)<<bitWidth - 1
+		if bitWidth == 64 {
+			mask = math.MaxUint64
+		}
+		minValues := []int64{0, -1 << 51, 1<<51 - int64(mask) - 1, int64(gen.Uint64()), math.MinInt64}
+		for _, minValue := range minValues {
+			for _, n := range []int{1, 7, 8, 9, 31, 1024, 1031} {
+				var (
+					s       = scaling{exponent: gen.Intn(MaxExponent + 1), factor: gen.Intn(MaxFactor + 1)}
+					ints    = make([]int64, n)
+					ints32  = make([]int32, n)
+					got     = make([]float64, n)
+					want    = make([]float64, n)
+					got32   = make([]float32, n)
+					want32  = make([]float32, n)
+					factor  = powersOf10[s.factor]
+					invExp  = inversePowersOf10[s.exponent]
+					factor2 = powersOf10[min(s.factor, MaxFactorFloat32)]
+					invExp2 = inversePowersOf10[min(s.exponent, MaxExponentFloat32)]
+				)
+				for i := range ints {
+					ints[i] = int64(gen.Uint64() & mask)
+					ints32[i] = int32(ints[i])
+				}
+				decodeIntegers(got, ints, minValue, bitWidth, s)
+				decodeIntegersGeneric(want, ints, minValue, factor, invExp)
+				for i := range got {
+					if math.Float64bits(got[i]) != math.Float64bits(want[i]) {
+						t.Fatalf("bit width %d, min %d: value %d is %v, want %v", bitWidth, minValue, i, got[i], want[i])
+					}
+				}
+
+				s32 := scaling{exponent: min(s.exponent, MaxExponentFloat32), factor: min(s.factor, MaxFactorFloat32)}
+				decodeIntegersFloat32(got32, ints32, int32(minValue), s32)
+				decodeIntegersFloat32Generic(want32, ints32, int32(minValue), factor2, invExp2)
+				for i := range got32 {
+					if math.Float32bits(got32[i]) != math.Float32bits(want32[i]) {
+						t.Fatalf("bit width %d, min %d: float32 value %d is %v, want %v", bitWidth, minValue, i, got32[i], want32[i])
+					}
+				}
+
+				// Integers are decoded in place.
+				decodeIntegers(unsafecast.Slice[float64](ints), ints, minValue, bitWidth, s)
+				if !slices.Equal(unsafecast.Slice[uint64](ints), unsafecast.Slice[uint64](want)) {
+					t.Fatalf("bit width %d, min %d: in-place decoding differs", bitWidth, minValue)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package alp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/parquet-go/bitpack"
+)
+
+var (
+	// ErrCorrupt is returned when encoded data is truncated or malformed.
+	ErrCorrupt = errors.New("corrupt data")
+	// ErrShortBuffer is returned when the destination cannot hold the
+	// decoded values.
+	ErrShortBuffer = errors.New("short buffer")
+)
+
+// DecodeChecked is like Decode but validates the encoded data before decoding
+// it, so that truncated or corrupted input returns an error wrapping
+// ErrCorrupt instead of panicking. It returns ErrShortBuffer if the capacity
+// of dst is smaller than the number of encoded values, and ErrChecksum if the
+// data has a checksum trailer which does not match.
+func DecodeChecked(dst []float64, data []byte) ([]float64, error) {
+	return decodeChecked(dst, data)
+}
+
+// DecodeFloat32Checked is like DecodeFloat32 but validates the encoded data
+// before decoding it, see DecodeChecked.
+func DecodeFloat32Checked(dst []float32, data []byte) ([]float32, error) {
+	return decodeChecked(dst, data)
+}
+
+func decodeChecked[F Float](dst []F, data []byte) ([]F, error) {
+	metadata, err := DecodeMetadataChecked(data)
+	if err != nil {
+		return dst[:0], err
+	}
+	if metadata.Checksum {
+		if data, err = verifyChecksum(data); err != nil {
+			return dst[:0], err
+		}
+	}
+	if metadata.Stream {
+		return dst[:0], fmt.Errorf("%w: streams have to be decoded with a stream decoder", ErrCorrupt)
+	}
+	if metadata.ValueType != valueTypeOf[F]() {
+		return dst[:0], fmt.Errorf("%w: unexpected value type %d", ErrCorrupt, metadata.ValueType)
+	}
+	if int(metadata.Count) > cap(dst) {
+		return dst[:0], fmt.Errorf("%w: %d values do not fit into %d", ErrShortBuffer, metadata.Count, cap(dst))
+	}
+	if err := checkBlock[F](data, metadata); err != nil {
+		return dst[:0], err
+	}
+	return decode(dst[:metadata.Count], data), nil
+}
+
+// DecodeMetadataChecked is like DecodeMetadata but returns an error wrapping
+// ErrCorrupt if data is too short or the metadata fields are out of range.
+func DecodeMetadataChecked(data []byte) (CompressionMetadata, error) {
+	if len(data) < MetadataSize {
+		return CompressionMetadata{}, fmt.Errorf("%w: %d bytes are too short for metadata", ErrCorrupt, len(data))
+	}
+	metadata := DecodeMetadata(data)
+	switch {
+	case metadata.ValueType > ValueTypeFloat32:
+		return metadata, fmt.Errorf("%w: unknown value type %d", ErrCorrupt, metadata.ValueType)
+	case metadata.EncodingType > EncodingRD:
+		return metadata, fmt.Errorf("%w: %w %d", ErrCorrupt, ErrInvalidEncoding, metadata.EncodingType)
+	case metadata.Count < 0:
+		return metadata, fmt.Errorf("%w: negative count %d", ErrCorrupt, metadata.Count)
+	case metadata.ExceptionCount < 0 || metadata.ExceptionCount > metadata.Count:
+		return metadata, fmt.Errorf("%w: %d exceptions for %d values", ErrCorrupt, metadata.ExceptionCount, metadata.Count)
+	case metadata.EncodingType == EncodingNone && metadata.Count != 0:
+		return metadata, fmt.Errorf("%w: %d values in an empty block", ErrCorrupt, metadata.Count)
+	}
+	return metadata, nil
+}
+
+// checkBlock validates the encoding parameters of a block and checks that data
+// is long enough to decode it.
+func checkBlock[F Float](data []byte, metadata CompressionMetadata) error {
+	count := int(metadata.Count)
+	switch metadata.EncodingType {
+	case EncodingALP:
+		exceptionsSize := int(metadata.ExceptionCount) * exceptionSize[F]()
+		if err := checkALPParameters[F](metadata); err != nil {
+			return err
+		}
+		if err := checkLength(data, MetadataSize+exceptionsSize+packedSize[F](count, metadata.BitWidth)); err != nil {
+			return err
+		}
+		return checkExceptionPositions(data[MetadataSize:], int(metadata.ExceptionCount), count, false)
+
+	case EncodingRD:
+		if isFloat32[F]() {
+			return fmt.Errorf("%w: ALP-RD is not used for float32 values", ErrCorrupt)
+		}
+		return checkRD(data, metadata)
+
+	case EncodingUncompressed:
+		return checkLength(data, MetadataSize+count*valueSize[F]())
+	}
+	return nil
+}
+
+// checkALPParameters checks that the exponent, factor and bit-width of an ALP
+// block are in range for the value type.
+func checkALPParameters[F Float](metadata CompressionMetadata) error {
+	maxExponent, maxFactor, maxBitWidth := MaxExponent, MaxFactor, 64
+	if isFloat32[F]() {
+		maxExponent, maxFactor, maxBitWidth = MaxExponentFloat32, MaxFactorFloat32, 32
+	}
+	switch {
+	case metadata.Exponent < MinExponent || int(metadata.Exponent) > maxExponent:
+		return fmt.Errorf("%w: exponent %d out of range", ErrCorrupt, metadata.Exponent)
+	case metadata.Factor < 0 || int(metadata.Factor) > maxFactor:
+		return fmt.Errorf("%w: factor %d out of range", ErrCorrupt, metadata.Factor)
+	case int(metadata.BitWidth) > maxBitWidth:
+		return fmt.Errorf("%w: bit width %d out of range", ErrCorrupt, metadata.BitWidth)
+	}
+	return nil
+}
+
+// checkRD validates the dictionary and the length of an ALP-RD block.
+func checkRD(data []byte, metadata CompressionMetadata) error {
+	rightBitWidth := int(metadata.BitWidth)
+	if rightBitWidth < rdMinRightBitWidth || rightBitWidth >= 64 {
+		return fmt.Errorf("%w: right bit width %d out of range", ErrCorrupt, rightBitWidth)
+	}
+	if err := checkLength(data, MetadataSize+1); err != nil {
+		return err
+	}
+	dictionarySize := int(data[MetadataSize])
+	if dictionarySize < 1 || dictionarySize > rdMaxDictionarySize {
+		return fmt.Errorf("%w: dictionary size %d out of range", ErrCorrupt, dictionarySize)
+	}
+
+	var (
+		count          = int(metadata.Count)
+		exceptionCount = int(metadata.ExceptionCount)
+		exceptionsFrom = MetadataSize + 1 + dictionarySize*2
+		indexBitWidth  = CalculateBitWidth(uint64(dictionarySize - 1))
+		indexesSize    = bitpack.ByteCount(uint(count * indexBitWidth))
+		rightsSize     = bitpack.ByteCount(uint(count*rightBitWidth)) + bitpack.PaddingInt64
+	)
+	if err := checkLength(data, exceptionsFrom+exceptionCount*RDExceptionSize+indexesSize+rightsSize); err != nil {
+		return err
+	}
+	return checkExceptionPositions(data[exceptionsFrom:], exceptionCount, count, false)
+}
+
+// checkExceptionPositions checks that the exception positions at the start of
+// src are smaller than count and, if sorted is set, strictly increasing.
+func checkExceptionPositions(src []byte, exceptionCount, count int, sorted bool) error {
+	prev := -1
+	for i := range exceptionCount {
+		pos := int(binary.LittleEndian.Uint32(src[i*4:]))
+		if pos >= count || (sorted && pos <= prev) {
+			return fmt.Errorf("%w: invalid exception position %d", ErrCorrupt, pos)
+		}
+		prev = pos
+	}
+	return nil
+}
+
+// checkLength checks that data holds at least size bytes.
+func checkLength(data []byte, size int) error {
+	if len(data) < size {
+		return fmt.Errorf("%w: %d bytes are too short for a block of %d bytes", ErrCorrupt, len(data), size)
+	}
+	return nil
+}
+
+// packedSize returns the number of bytes needed to unpack count integers of
+// the given bit-width into values of type F.
+func packedSize[F Float](count int, bitWidth uint8) int {
+	padding := bitpack.PaddingInt64
+	if isFloat32[F]() {
+		padding = bitpack.PaddingInt32
+	}
+	return bitpack.ByteCount(uint(count*int(bitWidth))) + padding
+}
+
+// checkStream validates the header, the checksum and the blocks of a stream,
+// so that all of its blocks can be decoded. It returns the metadata and the
+// block size of the stream.
+func checkStream[F Float](buf []byte) (CompressionMetadata, int, error) {
+	metadata, err := DecodeMetadataChecked(buf)
+	if err != nil {
+		return metadata, 0, err
+	}
+	if !metadata.Stream {
+		return metadata, 0, fmt.Errorf("%w: missing stream header", ErrCorrupt)
+	}
+	if metadata.Checksum {
+		if buf, err = verifyChecksum(buf); err != nil {
+			return metadata, 0, err
+		}
+	}
+	if err := checkLength(buf, StreamHeaderSize); err != nil {
+		return metadata, 0, err
+	}
+	blockSize := int(binary.LittleEndian.Uint32(buf[MetadataSize:]))
+	if blockSize < 1 || blockSize > MaxStreamBlockSize {
+		return metadata, 0, fmt.Errorf("%w: block size %d out of range", ErrCorrupt, blockSize)
+	}
+	if metadata.ValueType != valueTypeOf[F]() {
+		return metadata, 0, fmt.Errorf("%w: unexpected value type %d", ErrCorrupt, metadata.ValueType)
+	}
+	switch metadata.EncodingType {
+	case EncodingNone:
+		return metadata, blockSize, nil
+	case EncodingALP:
+	default:
+		return metadata, 0, fmt.Errorf("%w: unexpected encoding %d in stream", ErrCorrupt, metadata.EncodingType)
+	}
+
+	var (
+		count     = int(metadata.Count)
+		numBlocks = (count + blockSize - 1) / blockSize
+		indexEnd  = StreamHeaderSize + numBlocks*4
+	)
+	if err := checkLength(buf, indexEnd); err != nil {
+		return metadata, 0, err
+	}
+	blocks := buf[indexEnd:]
+	for i := range numBlocks {
+		offset := int(binary.LittleEndian.Uint32(buf[StreamHeaderSize+i*4:]))
+		if offset > len(blocks) {
+			return metadata, 0, fmt.Errorf("%w: block %d at offset %d exceeds %d bytes", ErrCorrupt, i, offset, len(blocks))
+		}
+		if err := checkStreamBlock[F](blocks[offset:], min(blockSize, count-i*blockSize)); err != nil {
+			return metadata, 0, fmt.Errorf("block %d: %w", i, err)
+		}
+	}
+	return metadata, blockSize, nil
+}
+
+// checkStreamBlock validates the header of a block of a stream with count
+// values and checks that data is long enough to decode it.
+func checkStreamBlock[F Float](data []byte, count int) error {
+	if err := checkLength(data, blockHeaderSize); err != nil {
+		return err
+	}
+	header := decodeBlockHeader(data, count)
+	data = data[blockHeaderSize:]
+	switch header.EncodingType {
+	case EncodingConstant:
+		return nil
+	case EncodingUncompressed:
+		return checkLength(data, count*valueSize[F]())
+	case EncodingALP:
+		if header.ExceptionCount < 0 || int(header.ExceptionCount) > count {
+			return fmt.Errorf("%w: %d exceptions for %d values", ErrCorrupt, header.ExceptionCount, count)
+		}
+		if err := checkALPParameters[F](header); err != nil {
+			return err
+		}
+		exceptionsSize := int(header.ExceptionCount) * exceptionSize[F]()
+		if err := checkLength(data, exceptionsSize+packedSize[F](count, header.BitWidth)); err != nil {
+			return err
+		}
+		return checkExceptionPositions(data, int(header.ExceptionCount), count, false)
+	default:
+		return fmt.Errorf("%w: %w %d", ErrCorrupt, ErrInvalidEncoding, header.EncodingType)
+	}
+}
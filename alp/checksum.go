@@ -0,0 +1,47 @@
+package alp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+const (
+	// ChecksumSize is the size in bytes of the checksum trailer.
+	ChecksumSize = 4
+
+	// checksumFlag is set in the value type byte of the metadata when the
+	// data ends with a checksum trailer.
+	checksumFlag = 0x80
+)
+
+// ErrChecksum is returned when the checksum of encoded data does not match.
+var ErrChecksum = errors.New("checksum mismatch")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// AppendChecksum flags data produced by Encode, EncodeFloat32 or the stream
+// encoders as checksummed and appends a CRC32C trailer over all of its bytes.
+// The checked decoders and the stream decoders verify the trailer and return
+// ErrChecksum if it does not match, while Decode ignores it.
+func AppendChecksum(data []byte) []byte {
+	if len(data) < MetadataSize {
+		return data
+	}
+	data[28] |= checksumFlag
+	return binary.LittleEndian.AppendUint32(data, crc32.Checksum(data, crcTable))
+}
+
+// verifyChecksum verifies the checksum trailer of data and returns data
+// without the trailer.
+func verifyChecksum(data []byte) ([]byte, error) {
+	if len(data) < MetadataSize+ChecksumSize {
+		return nil, fmt.Errorf("%w: %d bytes are too short for a checksum", ErrCorrupt, len(data))
+	}
+	data, trailer := data[:len(data)-ChecksumSize], data[len(data)-ChecksumSize:]
+	if crc32.Checksum(data, crcTable) != binary.LittleEndian.Uint32(trailer) {
+		return nil, ErrChecksum
+	}
+	return data, nil
+}
@@ -0,0 +1,63 @@
+//go:build amd64 && !purego
+
+package alp
+
+import "golang.org/x/sys/cpu"
+
+var (
+	hasAVX2     = cpu.X86.HasAVX2
+	hasAVX512DQ = cpu.X86.HasAVX512F && cpu.X86.HasAVX512DQ
+)
+
+// magicBits are the bits of 2^52 + 2^51. Adding an integer in [-2^51, 2^51)
+// to them gives the bits of the float64 2^52 + 2^51 + v, from which v is
+// recovered exactly by subtracting 2^52 + 2^51. AVX2 has no instruction to
+// convert int64 to float64, so this is used instead.
+const magicBits = 0x4338000000000000
+
+// decodeIntegersAVX512 decodes a multiple of 8 integers, see decodeIntegers.
+//
+//go:noescape
+func decodeIntegersAVX512(result []float64, ints []int64, minValue int64, factor, invExp float64)
+
+// decodeIntegersAVX2 decodes a multiple of 8 integers, which must be in
+// [-2^51, 2^51) after adding minValue. The bias is minValue + magicBits.
+//
+//go:noescape
+func decodeIntegersAVX2(result []float64, ints []int64, bias int64, factor, invExp float64)
+
+// decodeIntegersFloat32AVX2 decodes a multiple of 8 integers, see
+// decodeIntegersFloat32.
+//
+//go:noescape
+func decodeIntegersFloat32AVX2(result []float32, ints []int32, minValue int32, factor, invExp float64)
+
+func decodeIntegersArch(result []float64, ints []int64, minValue int64, bitWidth uint, factor, invExp float64) int {
+	n := len(result) &^ 7
+	ints = ints[:len(result)]
+	switch {
+	case hasAVX512DQ:
+		decodeIntegersAVX512(result[:n], ints[:n], minValue, factor, invExp)
+	case hasAVX2 && fitsMagic(minValue, bitWidth):
+		decodeIntegersAVX2(result[:n], ints[:n], minValue+magicBits, factor, invExp)
+	default:
+		return 0
+	}
+	return n
+}
+
+// fitsMagic reports whether all integers packed with bitWidth bits are in
+// [-2^51, 2^51) after adding minValue.
+func fitsMagic(minValue int64, bitWidth uint) bool {
+	const limit = 1 << 51
+	return bitWidth <= 51 && minValue >= -limit && minValue <= limit-1<<bitWidth
+}
+
+func decodeIntegersFloat32Arch(result []float32, ints []int32, minValue int32, factor, invExp float64) int {
+	if !hasAVX2 {
+		return 0
+	}
+	n := len(result) &^ 7
+	decodeIntegersFloat32AVX2(result[:n], ints[:n], minValue, factor, invExp)
+	return n
+}
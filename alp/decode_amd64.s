@@ -0,0 +1,114 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// The integers and the results may alias, each vector is loaded before its
+// results are stored. Scaling multiplies by the factor first and then by the
+// inverse exponent, like decodeValue, so that results are bit-exact.
+
+// func decodeIntegersAVX512(result []float64, ints []int64, minValue int64, factor, invExp float64)
+TEXT ·decodeIntegersAVX512(SB), NOSPLIT, $0-72
+	MOVQ result_base+0(FP), DI
+	MOVQ result_len+8(FP), CX
+	MOVQ ints_base+24(FP), SI
+
+	SHRQ $3, CX
+	JZ   avx512_done
+
+	VPBROADCASTQ minValue+48(FP), Z1
+	VBROADCASTSD factor+56(FP), Z2
+	VBROADCASTSD invExp+64(FP), Z3
+
+avx512_loop:
+	VPADDQ    (SI), Z1, Z0
+	VCVTQQ2PD Z0, Z0
+	VMULPD    Z2, Z0, Z0
+	VMULPD    Z3, Z0, Z0
+	VMOVUPD   Z0, (DI)
+
+	ADDQ $64, SI
+	ADDQ $64, DI
+	DECQ CX
+	JNZ  avx512_loop
+
+	VZEROUPPER
+
+avx512_done:
+	RET
+
+// func decodeIntegersAVX2(result []float64, ints []int64, bias int64, factor, invExp float64)
+TEXT ·decodeIntegersAVX2(SB), NOSPLIT, $0-72
+	MOVQ result_base+0(FP), DI
+	MOVQ result_len+8(FP), CX
+	MOVQ ints_base+24(FP), SI
+
+	SHRQ $3, CX
+	JZ   avx2_done
+
+	VPBROADCASTQ bias+48(FP), Y1
+	VBROADCASTSD factor+56(FP), Y2
+	VBROADCASTSD invExp+64(FP), Y3
+	MOVQ         $0x4338000000000000, AX
+	MOVQ         AX, X4
+	VPBROADCASTQ X4, Y4 // 2^52 + 2^51
+
+avx2_loop:
+	VPADDQ  (SI), Y1, Y0
+	VPADDQ  32(SI), Y1, Y5
+	VSUBPD  Y4, Y0, Y0
+	VSUBPD  Y4, Y5, Y5
+	VMULPD  Y2, Y0, Y0
+	VMULPD  Y2, Y5, Y5
+	VMULPD  Y3, Y0, Y0
+	VMULPD  Y3, Y5, Y5
+	VMOVUPD Y0, (DI)
+	VMOVUPD Y5, 32(DI)
+
+	ADDQ $64, SI
+	ADDQ $64, DI
+	DECQ CX
+	JNZ  avx2_loop
+
+	VZEROUPPER
+
+avx2_done:
+	RET
+
+// func decodeIntegersFloat32AVX2(result []float32, ints []int32, minValue int32, factor, invExp float64)
+TEXT ·decodeIntegersFloat32AVX2(SB), NOSPLIT, $0-72
+	MOVQ result_base+0(FP), DI
+	MOVQ result_len+8(FP), CX
+	MOVQ ints_base+24(FP), SI
+
+	SHRQ $3, CX
+	JZ   float32_done
+
+	MOVL         minValue+48(FP), AX
+	MOVL         AX, X1
+	VPBROADCASTD X1, Y1
+	VBROADCASTSD factor+56(FP), Y2
+	VBROADCASTSD invExp+64(FP), Y3
+
+float32_loop:
+	VPADDD       (SI), Y1, Y0
+	VEXTRACTI128 $1, Y0, X6
+	VCVTDQ2PD    X0, Y5
+	VCVTDQ2PD    X6, Y6
+	VMULPD       Y2, Y5, Y5
+	VMULPD       Y2, Y6, Y6
+	VMULPD       Y3, Y5, Y5
+	VMULPD       Y3, Y6, Y6
+	VCVTPD2PSY   Y5, X5
+	VCVTPD2PSY   Y6, X6
+	VMOVUPS      X5, (DI)
+	VMOVUPS      X6, 16(DI)
+
+	ADDQ $32, SI
+	ADDQ $32, DI
+	DECQ CX
+	JNZ  float32_loop
+
+	VZEROUPPER
+
+float32_done:
+	RET
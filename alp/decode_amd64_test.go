@@ -0,0 +1,14 @@
+//go:build amd64 && !purego
+
+package alp
+
+import "testing"
+
+func TestDecodeIntegersAVX2(t *testing.T) {
+	if !hasAVX2 {
+		t.Skip("AVX2 is not supported")
+	}
+	defer func(avx512 bool) { hasAVX512DQ = avx512 }(hasAVX512DQ)
+	hasAVX512DQ = false
+	testDecodeIntegers(t)
+}
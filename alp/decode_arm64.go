@@ -0,0 +1,26 @@
+//go:build arm64 && !purego
+
+package alp
+
+// decodeIntegersNEON decodes a multiple of 8 integers, see decodeIntegers.
+//
+//go:noescape
+func decodeIntegersNEON(result []float64, ints []int64, minValue int64, factor, invExp float64)
+
+// decodeIntegersFloat32NEON decodes a multiple of 8 integers, see
+// decodeIntegersFloat32.
+//
+//go:noescape
+func decodeIntegersFloat32NEON(result []float32, ints []int32, minValue int32, factor, invExp float64)
+
+func decodeIntegersArch(result []float64, ints []int64, minValue int64, bitWidth uint, factor, invExp float64) int {
+	n := len(result) &^ 7
+	decodeIntegersNEON(result[:n], ints[:n], minValue, factor, invExp)
+	return n
+}
+
+func decodeIntegersFloat32Arch(result []float32, ints []int32, minValue int32, factor, invExp float64) int {
+	n := len(result) &^ 7
+	decodeIntegersFloat32NEON(result[:n], ints[:n], minValue, factor, invExp)
+	return n
+}
@@ -0,0 +1,106 @@
+//go:build arm64 && !purego
+
+#include "textflag.h"
+
+// These vector instructions are only supported by recent Go assemblers, so
+// they are encoded by hand. Arguments are vector register numbers.
+#define SCVTF_2D(n, d) WORD $(0x4E61D800 | (n)<<5 | (d))           // SCVTF Vd.2D, Vn.2D
+#define FMUL_2D(m, n, d) WORD $(0x6E60DC00 | (m)<<16 | (n)<<5 | (d)) // FMUL Vd.2D, Vn.2D, Vm.2D
+#define SXTL(n, d) WORD $(0x0F20A400 | (n)<<5 | (d))                // SXTL Vd.2D, Vn.2S
+#define SXTL2(n, d) WORD $(0x4F20A400 | (n)<<5 | (d))               // SXTL2 Vd.2D, Vn.4S
+#define FCVTN(n, d) WORD $(0x0E616800 | (n)<<5 | (d))               // FCVTN Vd.2S, Vn.2D
+#define FCVTN2(n, d) WORD $(0x4E616800 | (n)<<5 | (d))              // FCVTN2 Vd.4S, Vn.2D
+
+// The integers and the results may alias, each vector is loaded before its
+// results are stored. Scaling multiplies by the factor first and then by the
+// inverse exponent, like decodeValue, so that results are bit-exact.
+
+// func decodeIntegersNEON(result []float64, ints []int64, minValue int64, factor, invExp float64)
+TEXT ·decodeIntegersNEON(SB), NOSPLIT, $0-72
+	MOVD result_base+0(FP), R0
+	MOVD result_len+8(FP), R2
+	MOVD ints_base+24(FP), R1
+	MOVD minValue+48(FP), R3
+	MOVD factor+56(FP), R4
+	MOVD invExp+64(FP), R5
+
+	LSR $3, R2
+	CBZ R2, done
+
+	VDUP R3, V1.D2
+	VDUP R4, V2.D2
+	VDUP R5, V3.D2
+
+loop:
+	VLD1.P 64(R1), [V4.D2, V5.D2, V6.D2, V7.D2]
+	VADD   V1.D2, V4.D2, V4.D2
+	VADD   V1.D2, V5.D2, V5.D2
+	VADD   V1.D2, V6.D2, V6.D2
+	VADD   V1.D2, V7.D2, V7.D2
+	SCVTF_2D(4, 4)
+	SCVTF_2D(5, 5)
+	SCVTF_2D(6, 6)
+	SCVTF_2D(7, 7)
+	FMUL_2D(2, 4, 4)
+	FMUL_2D(2, 5, 5)
+	FMUL_2D(2, 6, 6)
+	FMUL_2D(2, 7, 7)
+	FMUL_2D(3, 4, 4)
+	FMUL_2D(3, 5, 5)
+	FMUL_2D(3, 6, 6)
+	FMUL_2D(3, 7, 7)
+	VST1.P [V4.D2, V5.D2, V6.D2, V7.D2], 64(R0)
+
+	SUBS $1, R2
+	BNE  loop
+
+done:
+	RET
+
+// func decodeIntegersFloat32NEON(result []float32, ints []int32, minValue int32, factor, invExp float64)
+TEXT ·decodeIntegersFloat32NEON(SB), NOSPLIT, $0-72
+	MOVD  result_base+0(FP), R0
+	MOVD  result_len+8(FP), R2
+	MOVD  ints_base+24(FP), R1
+	MOVW  minValue+48(FP), R3
+	MOVD  factor+56(FP), R4
+	MOVD  invExp+64(FP), R5
+
+	LSR $3, R2
+	CBZ R2, float32_done
+
+	VDUP R3, V1.S4
+	VDUP R4, V2.D2
+	VDUP R5, V3.D2
+
+float32_loop:
+	VLD1.P 32(R1), [V4.S4, V5.S4]
+	VADD   V1.S4, V4.S4, V4.S4
+	VADD   V1.S4, V5.S4, V5.S4
+	SXTL(4, 16)
+	SXTL2(4, 17)
+	SXTL(5, 18)
+	SXTL2(5, 19)
+	SCVTF_2D(16, 16)
+	SCVTF_2D(17, 17)
+	SCVTF_2D(18, 18)
+	SCVTF_2D(19, 19)
+	FMUL_2D(2, 16, 16)
+	FMUL_2D(2, 17, 17)
+	FMUL_2D(2, 18, 18)
+	FMUL_2D(2, 19, 19)
+	FMUL_2D(3, 16, 16)
+	FMUL_2D(3, 17, 17)
+	FMUL_2D(3, 18, 18)
+	FMUL_2D(3, 19, 19)
+	FCVTN(16, 4)
+	FCVTN2(17, 4)
+	FCVTN(18, 5)
+	FCVTN2(19, 5)
+	VST1.P [V4.S4, V5.S4], 32(R0)
+
+	SUBS $1, R2
+	BNE  float32_loop
+
+float32_done:
+	RET
@@ -0,0 +1,11 @@
+//go:build !(amd64 || arm64) || purego
+
+package alp
+
+func decodeIntegersArch(result []float64, ints []int64, minValue int64, bitWidth uint, factor, invExp float64) int {
+	return 0
+}
+
+func decodeIntegersFloat32Arch(result []float32, ints []int32, minValue int32, factor, invExp float64) int {
+	return 0
+}
@@ -0,0 +1,33 @@
+package alp
+
+// Encoder encodes values like Encode and EncodeFloat32, but keeps the scratch
+// buffers needed during encoding between calls, so that encoding allocates
+// nothing once the buffers have grown to the size of the input and dst has
+// enough capacity. The zero value is ready to use. An Encoder must not be
+// used concurrently.
+type Encoder struct {
+	ints       []int64  // Scaled integers, or ALP-RD dictionary indexes
+	exceptions []uint32 // Positions of values which cannot be scaled
+	positions  []uint32 // Positions of all exceptions, see applyFrameOfReference
+	sample     []int64  // Sampled integers, see findBounds
+	candidates []candidate
+	scalings   []scaling // Candidate scalings returned by findCandidates
+	ties       []scaling // Best scalings of a sample, see searchScaling
+
+	// Scratch buffers of ALP-RD.
+	lefts      []uint16
+	runs       []rdRun
+	dictionary []uint16 // Dictionary of the current split
+	best       []uint16 // Dictionary of the best split
+	rights     []uint64
+}
+
+// Encode compresses float64 values, see Encode.
+func (e *Encoder) Encode(dst []byte, src []float64) []byte {
+	return encode(e, dst, src)
+}
+
+// EncodeFloat32 compresses float32 values, see EncodeFloat32.
+func (e *Encoder) EncodeFloat32(dst []byte, src []float32) []byte {
+	return encode(e, dst, src)
+}
@@ -0,0 +1,156 @@
+package alp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+
+	"github.com/parquet-go/bitpack"
+	"github.com/parquet-go/bitpack/unsafecast"
+)
+
+// At returns the value at index i of ALP-encoded data. Values are bit-packed
+// with a fixed width, so only the bits of the value are decoded. At panics if
+// i is out of range.
+func At(data []byte, i int) float64 {
+	return at[float64](data, i)
+}
+
+// AtFloat32 returns the value at index i of ALP-encoded float32 data, see At.
+func AtFloat32(data []byte, i int) float32 {
+	return at[float32](data, i)
+}
+
+// DecodeRange decodes the values with indexes in [start, end) of ALP-encoded
+// data into dst, reusing its capacity. Only the bits of the requested values
+// are unpacked. DecodeRange panics if the range is out of bounds.
+func DecodeRange(dst []float64, data []byte, start, end int) []float64 {
+	return decodeRange(dst, data, start, end)
+}
+
+// DecodeRangeFloat32 decodes a range of values of ALP-encoded float32 data,
+// see DecodeRange.
+func DecodeRangeFloat32(dst []float32, data []byte, start, end int) []float32 {
+	return decodeRange(dst, data, start, end)
+}
+
+func at[F Float](data []byte, i int) F {
+	metadata := rangeMetadata[F](data)
+	if i < 0 || i >= int(metadata.Count) {
+		panic(fmt.Sprintf("alp: index %d out of range [0:%d]", i, metadata.Count))
+	}
+
+	switch metadata.EncodingType {
+	case EncodingConstant:
+		return floatFromBits[F](math.Float64bits(metadata.ConstantValue))
+
+	case EncodingALP:
+		var (
+			exceptionCount = int(metadata.ExceptionCount)
+			exceptions     = data[MetadataSize:]
+		)
+		if pos := exceptionIndex(exceptions, exceptionCount, i); pos >= 0 {
+			return readFloat[F](exceptions[exceptionCount*4+pos*valueSize[F]():])
+		}
+		var (
+			packed = data[MetadataSize+exceptionCount*exceptionSize[F]():]
+			v      = unpackAt(packed, i, uint(metadata.BitWidth))
+		)
+		if isFloat32[F]() {
+			return F(decodeValueFloat32(int32(v)+int32(metadata.FrameOfRef), metadata.scaling()))
+		}
+		return F(decodeValue(int64(v)+metadata.FrameOfRef, metadata.scaling()))
+
+	case EncodingRD:
+		return F(rdAt(data, metadata, i))
+
+	default:
+		return readFloat[F](data[MetadataSize+i*valueSize[F]():])
+	}
+}
+
+func decodeRange[F Float](dst []F, data []byte, start, end int) []F {
+	metadata := rangeMetadata[F](data)
+	if start < 0 || start > end || end > int(metadata.Count) {
+		panic(fmt.Sprintf("alp: range [%d:%d] out of range [0:%d]", start, end, metadata.Count))
+	}
+
+	// Leave room for the values unpacked before start, see unpackRange.
+	n := end - start
+	dst = slices.Grow(dst[:0], n+7)[:n]
+	switch metadata.EncodingType {
+	case EncodingConstant:
+		value := floatFromBits[F](math.Float64bits(metadata.ConstantValue))
+		for i := range dst {
+			dst[i] = value
+		}
+	case EncodingALP:
+		decodeALP(dst, data, metadata, start)
+	case EncodingRD:
+		decodeRD(unsafecast.Slice[float64](dst), data, metadata, start)
+	case EncodingUncompressed:
+		decodeUncompressed(dst, data, start)
+	}
+	return dst
+}
+
+// rangeMetadata decodes the metadata of data. Blocks which Decode would decode
+// to no values, such as blocks of another value type or streams, have a count
+// of zero.
+func rangeMetadata[F Float](data []byte) CompressionMetadata {
+	metadata := DecodeMetadata(data)
+	switch {
+	case metadata.ValueType != valueTypeOf[F](),
+		metadata.Stream,
+		metadata.EncodingType > EncodingRD,
+		metadata.EncodingType == EncodingRD && isFloat32[F]():
+		metadata.Count = 0
+	}
+	return metadata
+}
+
+// unpackRange unpacks the integers with indexes [start, start+len(dst)) of the
+// packed data into dst. Unpacking begins at the preceding multiple of 8, which
+// is the first index starting at a byte boundary for all bit-widths, so dst
+// must have capacity for 7 more integers if start is not a multiple of 8.
+func unpackRange[T int32 | int64](dst []T, src []byte, start int, bitWidth uint) {
+	aligned := start &^ 7
+	src = src[uint(aligned)*bitWidth/8:]
+	if aligned == start {
+		bitpack.Unpack(dst, src, bitWidth)
+		return
+	}
+	lead := start - aligned
+	buf := dst[:lead+len(dst)]
+	bitpack.Unpack(buf, src, bitWidth)
+	copy(dst, buf[lead:])
+}
+
+// unpackAt returns the integer at index i of the packed data. It reads up to
+// 9 bytes past the start of the integer, which is covered by the padding of
+// the packed data.
+func unpackAt(src []byte, i int, bitWidth uint) uint64 {
+	bit := uint(i) * bitWidth
+	src = src[bit/8:]
+	shift := bit % 8
+	v := binary.LittleEndian.Uint64(src) >> shift
+	if shift+bitWidth > 64 {
+		v |= uint64(src[8]) << (64 - shift)
+	}
+	return v & (uint64(1)<<bitWidth - 1)
+}
+
+// exceptionIndex returns the index of the last exception at position pos, or
+// -1 if the value at pos is not an exception. Positions are written in
+// increasing order, so they are searched with a binary search.
+func exceptionIndex(src []byte, count int, pos int) int {
+	i := sort.Search(count, func(i int) bool {
+		return int(binary.LittleEndian.Uint32(src[i*4:])) > pos
+	})
+	if i == 0 || int(binary.LittleEndian.Uint32(src[(i-1)*4:])) != pos {
+		return -1
+	}
+	return i - 1
+}
@@ -0,0 +1,256 @@
+package alp
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+const (
+	// VectorSize is the number of values in a vector when sampling candidate
+	// exponent and factor combinations.
+	VectorSize = 1024
+
+	// sampledVectors is the number of vectors sampled when selecting candidates.
+	sampledVectors = 8
+	// vectorSamples is the number of values sampled from each vector.
+	vectorSamples = 32
+	// maxCandidates is the maximum number of candidates kept after the first
+	// sampling level.
+	maxCandidates = 5
+	// maxStaleCandidates is the number of candidates in a row which may fail to
+	// improve on the best one before the second sampling level stops.
+	maxStaleCandidates = 2
+)
+
+// scaling is the exponent and factor used to convert values to integers as
+// round(v * 10^exponent * 10^-factor).
+type scaling struct {
+	exponent int
+	factor   int
+}
+
+// findBestScaling finds the exponent and factor which minimize the estimated
+// encoded size, taking into account the cost of exceptions. Following the ALP
+// paper, candidates are first selected on small samples of vectors spread
+// across the data and the best candidate is then chosen on a larger sample.
+// Data of at most one vector is searched on a single small sample instead,
+// and only the combinations which tie on it are compared on the larger one.
+// It returns the scaling along with its estimated size in bits for the
+// sample, which is math.MaxInt if no combination compresses the data.
+func findBestScaling[F Float](e *Encoder, data []F) (scaling, int) {
+	if len(data) == 0 {
+		return scaling{}, 0
+	}
+	if len(data) > VectorSize {
+		return chooseScaling(data, findCandidates(e, data))
+	}
+	e.ties, _ = searchScaling(e.ties[:0], data, vectorSamples)
+	return chooseTie(data, e.ties)
+}
+
+// candidate is a scaling along with the number of sampled vectors for which
+// it was the best.
+type candidate struct {
+	scaling scaling
+	count   int
+}
+
+// findCandidates returns the combinations which perform best on samples of
+// vectors spread across the data, ordered by how often they were the best.
+// Of the combinations which tie on a vector, the one chosen by chooseTie
+// counts as its best. The returned slice is a scratch buffer of e.
+func findCandidates[F Float](e *Encoder, data []F) []scaling {
+	var (
+		numVectors = (len(data) + VectorSize - 1) / VectorSize
+		numSampled = min(numVectors, sampledVectors)
+		candidates = e.candidates[:0]
+	)
+	for i := range numSampled {
+		from := i * numVectors / numSampled * VectorSize
+		vector := data[from:min(from+VectorSize, len(data))]
+
+		e.ties, _ = searchScaling(e.ties[:0], vector, vectorSamples)
+		if len(e.ties) == 0 {
+			continue
+		}
+		best, _ := chooseTie(vector, e.ties)
+		idx := slices.IndexFunc(candidates, func(c candidate) bool { return c.scaling == best })
+		if idx < 0 {
+			candidates = append(candidates, candidate{scaling: best, count: 1})
+			continue
+		}
+		candidates[idx].count++
+	}
+	// Equally good candidates are tried from the smallest exponent, which
+	// chooseScaling keeps on ties.
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		return cmp.Or(
+			cmp.Compare(b.count, a.count),
+			cmp.Compare(a.scaling.exponent, b.scaling.exponent),
+			cmp.Compare(a.scaling.factor, b.scaling.factor),
+		)
+	})
+
+	result := e.scalings[:0]
+	for _, c := range candidates[:min(len(candidates), maxCandidates)] {
+		result = append(result, c.scaling)
+	}
+	e.candidates, e.scalings = candidates, result
+	return result
+}
+
+// searchScaling searches the exponent and factor combinations on sampleSize
+// values sampled from data. It appends the combinations with the lowest
+// estimated cost to dst, by decreasing exponent, and returns the cost. Factors
+// go up to the exponent as in the ALP paper; a zero exponent can also use
+// larger factors to shrink values with trailing zeros.
+//
+// Instead of trying all combinations, the factors of each exponent are pruned:
+// they are tried from the largest one down, and smaller factors only widen the
+// values once a factor causes no exceptions, or its values alone are too wide
+// to improve on the best combination. Like the candidates of chooseScaling,
+// the factors also stop once maxStaleCandidates of them in a row do not improve
+// on the best one of the exponent. Once the best combination causes no
+// exceptions, lower exponents only try the factor which keeps as many digits,
+// apart from the zero exponent.
+//
+// Combinations needing more bits per value than the right part of ALP-RD, or
+// than raw float32 values, are abandoned early, and nothing is appended if no
+// combination is cheaper.
+func searchScaling[F Float](dst []scaling, data []F, sampleSize int) ([]scaling, int) {
+	var (
+		offset        = len(dst)
+		exceptionBits = exceptionSize[F]() * 8
+		minCost       = min(len(data), sampleSize) * rdMinRightBitWidth
+		maxExponent   = MaxExponent
+		zeroFactor    = MaxFactor
+		exact         = false
+	)
+	if isFloat32[F]() {
+		minCost = min(len(data), sampleSize) * 32
+		maxExponent, zeroFactor = MaxExponentFloat32, MaxFactorFloat32
+	}
+	for exponent := maxExponent; exponent >= MinExponent; exponent-- {
+		maxFactor := exponent
+		if exponent == 0 {
+			maxFactor = zeroFactor
+		}
+		if exact && exponent > 0 {
+			// The best combination converts all sampled values, so lower
+			// exponents can only tie with it by keeping as many digits. Only
+			// the larger factors of the zero exponent can drop digits.
+			best := dst[len(dst)-1]
+			factor := exponent - best.exponent + best.factor
+			if factor < 0 {
+				continue
+			}
+			s := scaling{exponent: exponent, factor: factor}
+			if cost, _ := estimateCost(data, sampleSize, s, minCost); cost == minCost {
+				dst = append(dst, s)
+			}
+			continue
+		}
+		var (
+			exponentCost = math.MaxInt
+			stale        = -1
+		)
+		for factor := maxFactor; factor >= 0 && stale < maxStaleCandidates; factor-- {
+			var (
+				s                = scaling{exponent: exponent, factor: factor}
+				limit            = minCost
+				cost, exceptions = estimateCost(data, sampleSize, s, limit)
+			)
+			switch {
+			case cost < minCost:
+				dst, minCost, exact = append(dst[:offset], s), cost, exceptions == 0
+			case cost == minCost:
+				dst = append(dst, s)
+			}
+			if exceptions == 0 || cost-exceptions*exceptionBits > limit {
+				break
+			}
+			switch {
+			case cost <= limit && cost < exponentCost:
+				exponentCost, stale = cost, 0
+			case stale >= 0:
+				stale++
+			}
+		}
+	}
+	return dst, minCost
+}
+
+// chooseScaling picks the candidate with the lowest estimated cost on a
+// sample of SamplingSize values, and the first one of equally good ones.
+// Candidates are ordered by how likely they are to be the best, so as in the
+// ALP paper the search stops once maxStaleCandidates of them in a row do not
+// improve on the best one.
+func chooseScaling[F Float](data []F, candidates []scaling) (scaling, int) {
+	var (
+		best    scaling
+		minCost = math.MaxInt
+		stale   = 0
+	)
+	for _, s := range candidates {
+		if cost, _ := estimateCost(data, SamplingSize, s, minCost); cost < minCost {
+			best, minCost, stale = s, cost, 0
+		} else if stale++; stale == maxStaleCandidates {
+			break
+		}
+	}
+	return best, minCost
+}
+
+// chooseTie picks the combination with the lowest estimated cost on a sample
+// of SamplingSize values out of ties, which searchScaling returned by
+// decreasing exponent. Ties scale values alike, so they only differ by the
+// values which a small sample missed, and the first one from the smallest
+// exponent which causes no exceptions is the best.
+func chooseTie[F Float](data []F, ties []scaling) (scaling, int) {
+	var (
+		best    scaling
+		minCost = math.MaxInt
+	)
+	for _, s := range slices.Backward(ties) {
+		cost, exceptions := estimateCost(data, SamplingSize, s, minCost)
+		if cost < minCost {
+			best, minCost = s, cost
+		}
+		if exceptions == 0 {
+			break
+		}
+	}
+	return best, minCost
+}
+
+// estimateCost estimates the encoded size in bits of up to sampleSize values
+// sampled evenly from data, and counts the sampled values which become
+// exceptions. Estimation stops as soon as the cost exceeds limit, in which
+// case a lower bound of the cost and of the exceptions is returned.
+func estimateCost[F Float](data []F, sampleSize int, s scaling, limit int) (int, int) {
+	sampleSize = min(len(data), sampleSize)
+
+	var (
+		minValue      = int64(math.MaxInt64)
+		maxValue      = int64(math.MinInt64)
+		exceptions    = 0
+		exceptionCost = 0
+		packedCost    = 0
+	)
+	for i := range sampleSize {
+		intValue, ok := encodeFloat(data[i*len(data)/sampleSize], s)
+		if ok {
+			minValue = min(minValue, intValue)
+			maxValue = max(maxValue, intValue)
+			packedCost = sampleSize * CalculateBitWidth(uint64(maxValue-minValue))
+		} else {
+			exceptions++
+			exceptionCost += exceptionSize[F]() * 8
+		}
+		if exceptionCost+packedCost > limit {
+			return exceptionCost + packedCost, exceptions
+		}
+	}
+	return exceptionCost + packedCost, exceptions
+}
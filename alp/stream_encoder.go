@@ -0,0 +1,145 @@
+package alp
+
+import (
+	"encoding/binary"
+
+	"github.com/parquet-go/bitpack"
+)
+
+// StreamEncoder incrementally encodes float64 values into the stream format
+// of StreamEncode. Values are buffered until a block is full, which is then
+// encoded right away and returned by the next call to Flush. Finish encodes
+// the last block and returns the header of the stream along with its block
+// index, which precede the blocks. Only the values of a single block, the
+// blocks encoded since the last call to Flush and 4 bytes per block for the
+// index are held in memory. Since the whole input is not known up front, each
+// block samples its own candidate exponents and factors.
+//
+// The zero value encodes blocks of DefaultBlockSize values.
+type StreamEncoder struct {
+	streamEncoder[float64]
+}
+
+// NewStreamEncoder returns an encoder writing blocks of blockSize values,
+// which must be between 1 and MaxStreamBlockSize.
+func NewStreamEncoder(blockSize int) *StreamEncoder {
+	e := &StreamEncoder{}
+	e.init(blockSize)
+	return e
+}
+
+// streamEncoder encodes values of type F block by block.
+type streamEncoder[F Float] struct {
+	blockSize int
+	header    []byte   // Buffer for the header returned by Finish
+	blocks    []byte   // Encoded blocks which were not returned yet
+	spare     []byte   // Buffer of the blocks returned last, reused next
+	offsets   []uint32 // Offsets of the encoded blocks
+	size      int      // Size in bytes of the encoded blocks
+	values    []F      // Values of the block which is not full yet
+	count     int      // Number of values appended since the last reset
+	scratch   Encoder  // Scratch buffers for encoding blocks
+}
+
+func (e *streamEncoder[F]) init(blockSize int) {
+	checkBlockSize(blockSize)
+	e.blockSize = blockSize
+	e.values = make([]F, 0, blockSize)
+}
+
+// Reset discards all appended values and starts a new stream. The blocks
+// returned by the next call to Flush or Finish are written to dst, reusing
+// its capacity.
+func (e *streamEncoder[F]) Reset(dst []byte) {
+	e.blocks = dst[:0]
+	e.reset()
+}
+
+func (e *streamEncoder[F]) reset() {
+	e.offsets = e.offsets[:0]
+	e.size = 0
+	e.values = e.values[:0]
+	e.count = 0
+}
+
+// Append appends a single value, encoding the current block if it is full.
+func (e *streamEncoder[F]) Append(v F) {
+	if e.blockSize == 0 {
+		e.init(DefaultBlockSize)
+	}
+	e.values = append(e.values, v)
+	e.count++
+	if len(e.values) == e.blockSize {
+		e.encodeBlock()
+	}
+}
+
+// Write appends values, encoding blocks as they fill.
+func (e *streamEncoder[F]) Write(values []F) {
+	if e.blockSize == 0 {
+		e.init(DefaultBlockSize)
+	}
+	for len(values) > 0 {
+		n := min(len(values), e.blockSize-len(e.values))
+		e.values = append(e.values, values[:n]...)
+		e.count += n
+		values = values[n:]
+		if len(e.values) == e.blockSize {
+			e.encodeBlock()
+		}
+	}
+}
+
+// Len returns the number of values appended since the last reset.
+func (e *streamEncoder[F]) Len() int {
+	return e.count
+}
+
+// Flush returns the blocks which were encoded since the last call to Flush,
+// in the order of the stream, and releases them so that they can be written
+// out as they fill. The returned slice is valid until the next call to Flush
+// or Finish, which reuse it.
+func (e *streamEncoder[F]) Flush() []byte {
+	return e.swap()
+}
+
+// Finish encodes the values of the block which is not full yet as the last
+// block, and returns the header of the stream along with its block index, and
+// the blocks which were not returned by Flush yet followed by the padding of
+// the stream. The stream of all values appended since the last reset is the
+// header followed by the blocks returned by Flush and Finish, and can be
+// decoded with a stream decoder. The encoder is then reset to start a new
+// stream. The returned slices are valid until the next call to Flush or
+// Finish, which reuse them.
+func (e *streamEncoder[F]) Finish() (header, blocks []byte) {
+	if e.blockSize == 0 {
+		e.init(DefaultBlockSize)
+	}
+	if len(e.values) > 0 {
+		e.encodeBlock()
+	}
+	header = appendStreamHeader[F](e.header[:0], e.count, e.blockSize)
+	for i, offset := range e.offsets {
+		binary.LittleEndian.PutUint32(header[StreamHeaderSize+i*4:], offset)
+	}
+	e.header = header
+	e.blocks = append(e.blocks, make([]byte, bitpack.PaddingInt64)...)
+	e.reset()
+	return header, e.swap()
+}
+
+// swap returns the encoded blocks and continues with the spare buffer.
+func (e *streamEncoder[F]) swap() []byte {
+	blocks := e.blocks
+	e.blocks, e.spare = e.spare[:0], blocks
+	return blocks
+}
+
+// encodeBlock encodes the buffered values as a block.
+func (e *streamEncoder[F]) encodeBlock() {
+	e.offsets = append(e.offsets, uint32(e.size))
+	n := len(e.blocks)
+	e.blocks = appendBlock(&e.scratch, e.blocks, e.values, findCandidates(&e.scratch, e.values))
+	e.size += len(e.blocks) - n
+	e.values = e.values[:0]
+}
@@ -3,6 +3,7 @@ module benchmarks
 go 1.25.1
 
 require (
+	github.com/fpetkovski/tscodec-go v0.0.0-00010101000000-000000000000
 	github.com/prometheus/prometheus v0.307.3
 	github.com/stretchr/testify v1.11.1
 )
@@ -17,3 +18,5 @@ require (
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace github.com/fpetkovski/tscodec-go => ../
@@ -73,9 +73,13 @@ func BenchmarkFloats(b *testing.B) {
 			tsc := make([]byte, numSamples*8)
 			fsc := make([]byte, numSamples*8)
 
+			var (
+				tsEncoder dod.Encoder
+				vsEncoder alp.Encoder
+			)
 			for b.Loop() {
-				tsc = dod.EncodeInt64(tsc, ts)
-				fsc = alp.Encode(fsc, vs)
+				tsc = tsEncoder.EncodeInt64(tsc, ts)
+				fsc = vsEncoder.Encode(fsc, vs)
 
 				b.ReportMetric(float64(len(tsc)+len(fsc)), "compressed_bytes")
 			}
@@ -124,7 +128,7 @@ func BenchmarkFloats(b *testing.B) {
 			alpDecoder := alp.StreamDecoder{}
 			for b.Loop() {
 				_ = dod.DecodeInt64(ints[:], tsc)
-				alpDecoder.Reset(fsc, 8)
+				alpDecoder.Reset(fsc)
 
 				for {
 					_, err := alpDecoder.Decode(floatsDst[:])
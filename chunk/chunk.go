@@ -0,0 +1,220 @@
+// Package chunk encodes series of (timestamp, value) samples into a single
+// self-contained byte slice. Timestamps are encoded with delta-of-delta and
+// values with ALP, each in its own length-prefixed section:
+//
+//	timestamps length (uint32)
+//	timestamps (dod)
+//	values length (uint32)
+//	values (alp)
+package chunk
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/fpetkovski/tscodec-go/alp"
+	"github.com/fpetkovski/tscodec-go/dod"
+)
+
+const (
+	// MaxSamples is the maximum number of samples in a chunk.
+	MaxSamples = dod.BlockSize
+
+	// sectionHeaderSize is the size in bytes of the length of a section.
+	sectionHeaderSize = 4
+)
+
+var (
+	// ErrCorrupt is returned when the sections of a chunk are truncated or
+	// disagree with each other. Errors found by the codecs are returned as is.
+	ErrCorrupt = errors.New("corrupt chunk")
+	// ErrFull is returned when appending to a chunk with MaxSamples samples.
+	ErrFull = errors.New("chunk is full")
+)
+
+// Encode encodes the timestamps and values into a chunk, reusing the capacity
+// of dst. ts and vs must have the same length of at most MaxSamples.
+func Encode(dst []byte, ts []int64, vs []float64) ([]byte, error) {
+	if len(ts) != len(vs) {
+		return dst[:0], fmt.Errorf("%d timestamps do not match %d values", len(ts), len(vs))
+	}
+	if len(ts) > MaxSamples {
+		return dst[:0], fmt.Errorf("%w: %d samples exceed %d", ErrFull, len(ts), MaxSamples)
+	}
+
+	dst = appendSection(dst[:0], func(b []byte) []byte { return dod.EncodeInt64(b, ts) })
+	dst = appendSection(dst, func(b []byte) []byte { return alp.Encode(b, vs) })
+	return dst, nil
+}
+
+// appendSection appends a section encoded by encode to dst. The section is
+// encoded directly into the spare capacity of dst when it fits.
+func appendSection(dst []byte, encode func([]byte) []byte) []byte {
+	offset := len(dst)
+	dst = binary.LittleEndian.AppendUint32(dst, 0)
+
+	body := dst[len(dst):]
+	section := encode(body)
+	if len(section) > 0 && cap(body) > 0 && &section[0] == &body[:1][0] {
+		dst = dst[:len(dst)+len(section)]
+	} else {
+		dst = append(dst, section...)
+	}
+	binary.LittleEndian.PutUint32(dst[offset:], uint32(len(section)))
+	return dst
+}
+
+// Decode decodes a chunk into ts and vs, reusing their capacity. An empty
+// data slice decodes to an empty chunk.
+func Decode(ts []int64, vs []float64, data []byte) ([]int64, []float64, error) {
+	if len(data) == 0 {
+		return ts[:0], vs[:0], nil
+	}
+	tsSection, vsSection, err := splitSections(data)
+	if err != nil {
+		return ts[:0], vs[:0], err
+	}
+
+	metadata, err := alp.DecodeMetadataChecked(vsSection)
+	if err != nil {
+		return ts[:0], vs[:0], err
+	}
+	count := int(metadata.Count)
+	if count > MaxSamples {
+		return ts[:0], vs[:0], fmt.Errorf("%w: %d samples exceed %d", ErrCorrupt, count, MaxSamples)
+	}
+
+	vs, err = alp.DecodeChecked(slices.Grow(vs[:0], count), vsSection)
+	if err != nil {
+		return ts[:0], vs[:0], err
+	}
+	ts = slices.Grow(ts[:0], count)[:count]
+	n, err := dod.DecodeInt64Checked(ts, tsSection)
+	if err != nil {
+		return ts[:0], vs[:0], err
+	}
+	if int(n) != count {
+		return ts[:0], vs[:0], fmt.Errorf("%w: %d timestamps do not match %d values", ErrCorrupt, n, count)
+	}
+	return ts, vs, nil
+}
+
+// splitSections returns the timestamps and values sections of a chunk.
+func splitSections(data []byte) (tsSection, vsSection []byte, err error) {
+	tsSection, data, err = readSection(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	vsSection, data, err = readSection(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) != 0 {
+		return nil, nil, fmt.Errorf("%w: %d trailing bytes", ErrCorrupt, len(data))
+	}
+	return tsSection, vsSection, nil
+}
+
+// readSection reads a length-prefixed section and returns it along with the
+// remaining data.
+func readSection(data []byte) (section, rest []byte, err error) {
+	if len(data) < sectionHeaderSize {
+		return nil, nil, fmt.Errorf("%w: %d bytes are too short for a section", ErrCorrupt, len(data))
+	}
+	size := binary.LittleEndian.Uint32(data)
+	data = data[sectionHeaderSize:]
+	if uint64(size) > uint64(len(data)) {
+		return nil, nil, fmt.Errorf("%w: section of %d bytes exceeds %d", ErrCorrupt, size, len(data))
+	}
+	return data[:size], data[size:], nil
+}
+
+// Appender accumulates samples and encodes them into a chunk.
+type Appender struct {
+	ts []int64
+	vs []float64
+}
+
+// NewAppender returns an appender which continues appending to the samples
+// of an existing chunk. data can be empty to start a new chunk.
+func NewAppender(data []byte) (*Appender, error) {
+	a := &Appender{}
+	if err := a.Reset(data); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Reset replaces the samples of the appender with the samples of a chunk.
+func (a *Appender) Reset(data []byte) error {
+	var err error
+	a.ts, a.vs, err = Decode(a.ts, a.vs, data)
+	return err
+}
+
+// Append adds a sample. It returns ErrFull if the chunk has MaxSamples
+// samples.
+func (a *Appender) Append(t int64, v float64) error {
+	if len(a.ts) >= MaxSamples {
+		return ErrFull
+	}
+	a.ts = append(a.ts, t)
+	a.vs = append(a.vs, v)
+	return nil
+}
+
+// Len returns the number of samples in the appender.
+func (a *Appender) Len() int {
+	return len(a.ts)
+}
+
+// Encode encodes the samples into a chunk, reusing the capacity of dst.
+func (a *Appender) Encode(dst []byte) []byte {
+	// The appender never holds more than MaxSamples samples.
+	dst, _ = Encode(dst, a.ts, a.vs)
+	return dst
+}
+
+// Iterator iterates over the samples of a chunk.
+type Iterator struct {
+	ts  []int64
+	vs  []float64
+	i   int
+	err error
+}
+
+// NewIterator returns an iterator over the samples of a chunk.
+func NewIterator(data []byte) *Iterator {
+	it := &Iterator{}
+	it.Reset(data)
+	return it
+}
+
+// Reset prepares the iterator to iterate over the samples of data, reusing
+// its buffers. Errors found while decoding are returned by Err.
+func (it *Iterator) Reset(data []byte) {
+	it.ts, it.vs, it.err = Decode(it.ts, it.vs, data)
+	it.i = -1
+}
+
+// Next advances the iterator to the next sample and reports whether there is
+// one.
+func (it *Iterator) Next() bool {
+	if it.err != nil || it.i+1 >= len(it.ts) {
+		return false
+	}
+	it.i++
+	return true
+}
+
+// At returns the current sample.
+func (it *Iterator) At() (int64, float64) {
+	return it.ts[it.i], it.vs[it.i]
+}
+
+// Err returns the error found while decoding the chunk, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,187 @@
+package chunk
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/fpetkovski/tscodec-go/alp"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	gen := rand.New(rand.NewSource(42))
+	tests := []struct {
+		name string
+		ts   []int64
+		vs   []float64
+	}{
+		{name: "empty"},
+		{name: "single sample", ts: []int64{1700000000000}, vs: []float64{1.5}},
+		{
+			name: "scrape interval",
+			ts: func() []int64 {
+				ts := make([]int64, 120)
+				for i := range ts {
+					ts[i] = 1700000000000 + int64(i)*15000 + gen.Int63n(100)
+				}
+				return ts
+			}(),
+			vs: func() []float64 {
+				vs := make([]float64, 120)
+				for i := range vs {
+					vs[i] = math.Round(gen.NormFloat64()*1000) / 100
+				}
+				return vs
+			}(),
+		},
+		{
+			name: "full chunk",
+			ts: func() []int64 {
+				ts := make([]int64, MaxSamples)
+				for i := range ts {
+					ts[i] = int64(i)
+				}
+				return ts
+			}(),
+			vs: make([]float64, MaxSamples),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, buf := range [][]byte{nil, make([]byte, 0, 1<<16)} {
+				encoded, err := Encode(buf, tc.ts, tc.vs)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				ts, vs, err := Decode(nil, nil, encoded)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !slices.Equal(ts, tc.ts) || !slices.Equal(vs, tc.vs) {
+					t.Fatalf("samples are not equal: got: [%v %v] want: [%v %v]", ts, vs, tc.ts, tc.vs)
+				}
+
+				var i int
+				it := NewIterator(encoded)
+				for it.Next() {
+					ts, v := it.At()
+					if ts != tc.ts[i] || v != tc.vs[i] {
+						t.Fatalf("unexpected sample %d: got (%d, %v), want (%d, %v)", i, ts, v, tc.ts[i], tc.vs[i])
+					}
+					i++
+				}
+				if err := it.Err(); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if i != len(tc.ts) {
+					t.Fatalf("expected %d samples, got %d", len(tc.ts), i)
+				}
+			}
+		})
+	}
+}
+
+func TestAppender(t *testing.T) {
+	app, err := NewAppender(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := range 10 {
+		if err := app.Append(int64(i*1000), float64(i)/10); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	encoded := app.Encode(nil)
+
+	// Continue appending to the encoded chunk.
+	app, err = NewAppender(encoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if app.Len() != 10 {
+		t.Fatalf("expected 10 samples, got %d", app.Len())
+	}
+	if err := app.Append(10000, 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ts, vs, err := Decode(nil, nil, app.Encode(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(ts) != 11 || ts[10] != 10000 || vs[10] != 1 || vs[3] != 0.3 {
+		t.Fatalf("unexpected samples: %v %v", ts, vs)
+	}
+
+	for app.Len() < MaxSamples {
+		if err := app.Append(int64(app.Len()*1000), 0); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := app.Append(0, 0); !errors.Is(err, ErrFull) {
+		t.Fatalf("expected ErrFull, got %v", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	encoded, err := Encode(nil, []int64{1000, 2000, 3000}, []float64{1, 2, 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	mismatched, err := Encode(nil, []int64{1000, 2000}, []float64{1, 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Combine the timestamps of one chunk with the values of another.
+	tsSection, _, _ := splitSections(mismatched)
+	_, vsSection, _ := splitSections(encoded)
+	combined := appendSection(nil, func([]byte) []byte { return tsSection })
+	combined = appendSection(combined, func([]byte) []byte { return vsSection })
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{name: "short", data: encoded[:3], err: ErrCorrupt},
+		{name: "truncated", data: encoded[:len(encoded)-1], err: ErrCorrupt},
+		{name: "trailing bytes", data: append(slices.Clone(encoded), 0), err: ErrCorrupt},
+		{name: "mismatched sections", data: combined, err: ErrCorrupt},
+		{name: "corrupt values", data: func() []byte {
+			data := slices.Clone(encoded)
+			data[len(data)-len(vsSection)] = 42
+			return data
+		}(), err: alp.ErrCorrupt},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, _, err := Decode(nil, nil, tc.data); !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			it := NewIterator(tc.data)
+			if it.Next() || !errors.Is(it.Err(), tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, it.Err())
+			}
+		})
+	}
+
+	if _, err := Encode(nil, []int64{1}, nil); err == nil {
+		t.Fatal("expected error for mismatched lengths")
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	encoded, _ := Encode(nil, []int64{1000, 2000, 3000}, []float64{1.5, 2.5, 3.5})
+	f.Add(encoded)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		it := NewIterator(data)
+		for it.Next() {
+			it.At()
+		}
+	})
+}
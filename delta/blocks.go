@@ -0,0 +1,133 @@
+package delta
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+)
+
+// A single block holds at most Int64BlockSize values. Longer inputs are
+// encoded as a sequence of blocks:
+//
+//	value count (uint32)
+//	for each block:
+//	  block length in bytes (uint32)
+//	  block
+//
+// Every block but the last holds exactly Int64BlockSize values.
+
+// BlocksHeaderSize is the size in bytes of the header of a sequence of blocks.
+const BlocksHeaderSize = 4
+
+// EncodeInt64Blocks encodes any number of values by splitting them into blocks
+// of Int64BlockSize values, each encoded with EncodeInt64.
+func EncodeInt64Blocks(dst []byte, src []int64) []byte {
+	var e Encoder
+	return EncodeBlocks(dst, src, e.EncodeInt64)
+}
+
+// DecodeInt64Blocks decodes values encoded with EncodeInt64Blocks into dst,
+// growing it if needed.
+func DecodeInt64Blocks(dst []int64, src []byte) []int64 {
+	return DecodeBlocks(dst, src, DecodeInt64)
+}
+
+// DecodeInt64BlocksChecked is like DecodeInt64Blocks but validates src before
+// decoding it, see DecodeInt64Checked.
+func DecodeInt64BlocksChecked(dst []int64, src []byte) ([]int64, error) {
+	return DecodeBlocksChecked(dst, src, DecodeInt64Checked)
+}
+
+// EncodeInt32Blocks encodes any number of values by splitting them into blocks
+// of Int32BlockSize values, each encoded with EncodeInt32.
+func EncodeInt32Blocks(dst []byte, src []int32) []byte {
+	var e Encoder
+	return EncodeBlocks(dst, src, e.EncodeInt32)
+}
+
+// DecodeInt32Blocks decodes values encoded with EncodeInt32Blocks into dst,
+// growing it if needed.
+func DecodeInt32Blocks(dst []int32, src []byte) []int32 {
+	return DecodeBlocks(dst, src, DecodeInt32)
+}
+
+// DecodeInt32BlocksChecked is like DecodeInt32Blocks but validates src before
+// decoding it, see DecodeInt32Checked.
+func DecodeInt32BlocksChecked(dst []int32, src []byte) ([]int32, error) {
+	return DecodeBlocksChecked(dst, src, DecodeInt32Checked)
+}
+
+// EncodeBlocks encodes src as a sequence of blocks, encoding each block with
+// encode. It is shared with the dod package.
+func EncodeBlocks[T any](dst []byte, src []T, encode func([]byte, []T) []byte) []byte {
+	dst = binary.LittleEndian.AppendUint32(dst[:0], uint32(len(src)))
+	for len(src) > 0 {
+		n := min(len(src), Int64BlockSize)
+		dst = binary.LittleEndian.AppendUint32(dst, 0)
+		start := len(dst)
+		block := encode(dst[start:], src[:n])
+		// Append is a no-op copy if the block was encoded in place.
+		dst = append(dst, block...)
+		binary.LittleEndian.PutUint32(dst[start-4:], uint32(len(block)))
+		src = src[n:]
+	}
+	return dst
+}
+
+// DecodeBlocks decodes a sequence of blocks into dst, decoding each block with
+// decode. It is shared with the dod package.
+func DecodeBlocks[T any](dst []T, src []byte, decode func([]T, []byte) uint16) []T {
+	if len(src) == 0 {
+		return dst[:0]
+	}
+	count := int(binary.LittleEndian.Uint32(src))
+	dst = slices.Grow(dst[:0], count)[:count]
+	src = src[BlocksHeaderSize:]
+	for i := 0; i < count; {
+		size := binary.LittleEndian.Uint32(src)
+		i += int(decode(dst[i:], src[4:4+size]))
+		src = src[4+size:]
+	}
+	return dst
+}
+
+// DecodeBlocksChecked is like DecodeBlocks but validates the sequence of
+// blocks and decodes each block with the checked decoder.
+func DecodeBlocksChecked[T any](dst []T, src []byte, decode func([]T, []byte) (uint16, error)) ([]T, error) {
+	if len(src) == 0 {
+		return dst[:0], nil
+	}
+	if len(src) < BlocksHeaderSize {
+		return dst[:0], fmt.Errorf("%w: %d bytes are too short for a header", ErrCorrupt, len(src))
+	}
+	count := int(binary.LittleEndian.Uint32(src))
+	// Blocks are validated as they are decoded, so that memory is only
+	// allocated for values which are actually present.
+	dst = dst[:0]
+	for blocks := src[BlocksHeaderSize:]; len(blocks) > 0; {
+		if len(blocks) < 4 {
+			return dst[:0], fmt.Errorf("%w: truncated block length", ErrCorrupt)
+		}
+		size := binary.LittleEndian.Uint32(blocks)
+		blocks = blocks[4:]
+		if size < HeaderSize || uint64(size) > uint64(len(blocks)) {
+			return dst[:0], fmt.Errorf("%w: block of %d bytes in %d bytes", ErrCorrupt, size, len(blocks))
+		}
+		block := blocks[:size]
+		blocks = blocks[size:]
+
+		n := int(DecodeHeader(block).NumValues)
+		if n > Int64BlockSize || len(dst)+n > count {
+			return dst[:0], fmt.Errorf("%w: blocks hold more than %d values", ErrCorrupt, count)
+		}
+		i := len(dst)
+		dst = slices.Grow(dst, n)[:i+n]
+		if _, err := decode(dst[i:], block); err != nil {
+			return dst[:0], err
+		}
+	}
+	if len(dst) != count {
+		return dst[:0], fmt.Errorf("%w: blocks hold %d values instead of %d", ErrCorrupt, len(dst), count)
+	}
+	return dst, nil
+}
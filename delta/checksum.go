@@ -0,0 +1,48 @@
+package delta
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+const (
+	// ChecksumSize is the size in bytes of the checksum trailer.
+	ChecksumSize = 4
+
+	// checksumFlag is set in the bit-width byte of the header when the block
+	// ends with a checksum trailer. Bit-widths never exceed 64, so the high
+	// bit is otherwise unused.
+	checksumFlag = 0x80
+)
+
+// ErrChecksum is returned when the checksum of a block does not match.
+var ErrChecksum = errors.New("checksum mismatch")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// AppendChecksum flags a block produced by one of the encoders as checksummed
+// and appends a CRC32C trailer over all of its bytes. The checked decoders
+// verify the trailer and return ErrChecksum if it does not match, while the
+// other decoders ignore it. Empty blocks are returned as is.
+func AppendChecksum(block []byte) []byte {
+	if len(block) < HeaderSize {
+		return block
+	}
+	block[Int64SizeBytes+2] |= checksumFlag
+	return binary.LittleEndian.AppendUint32(block, crc32.Checksum(block, crcTable))
+}
+
+// verifyChecksum verifies the checksum trailer of a block and returns the
+// block without the trailer.
+func verifyChecksum(block []byte) ([]byte, error) {
+	if len(block) < HeaderSize+ChecksumSize {
+		return nil, fmt.Errorf("%w: %d bytes are too short for a checksum", ErrCorrupt, len(block))
+	}
+	block, trailer := block[:len(block)-ChecksumSize], block[len(block)-ChecksumSize:]
+	if crc32.Checksum(block, crcTable) != binary.LittleEndian.Uint32(trailer) {
+		return nil, ErrChecksum
+	}
+	return block, nil
+}
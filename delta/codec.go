@@ -0,0 +1,164 @@
+// Package delta encodes integers as the differences between consecutive
+// values. Each block stores the smallest difference in its header and packs
+// the distance of every difference from it with the bit width of the largest
+// distance.
+//
+// # Block size
+//
+// A block holds at most Int64BlockSize values, and the encoders of single
+// blocks panic on longer inputs. This is a breaking change: they used to
+// accept them and silently truncate the value count in the header. Longer
+// series are split into a sequence of blocks by EncodeInt64Blocks and
+// EncodeInt32Blocks.
+//
+// # Value range
+//
+// Values of every integer type are encoded losslessly over their whole
+// range. Differences of values narrower than 64 bits are exact in int64.
+// Differences of 64-bit values, such as math.MinInt64 following
+// math.MaxInt64 or uint64 values on both sides of 2^63, are computed modulo
+// 2^64. The distance of a difference from the smallest one is still in
+// [0, 2^64) and is packed with up to 64 bits, and decoding adds both back
+// modulo 2^64, which restores the original bits. A block of uint64 values is
+// therefore the block of the same bits encoded as int64.
+package delta
+
+import (
+	"encoding/binary"
+	"slices"
+	"unsafe"
+
+	"github.com/parquet-go/bitpack/unsafecast"
+)
+
+// Integer is the set of integer types which can be encoded.
+type Integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Encode encodes src into a single block, reusing the capacity of dst. The
+// first value is stored with the size of T and the deltas are bit-packed as
+// int64, so blocks of int64 and int32 values are the ones of EncodeInt64 and
+// EncodeInt32. Deltas of 64-bit values wrap around, see the package
+// documentation. Encode panics if src holds more than Int64BlockSize values.
+//
+// Sequences of blocks can be encoded by passing Encode to EncodeBlocks.
+func Encode[T Integer](dst []byte, src []T) []byte {
+	var e Encoder
+	return EncodeWith(&e, dst, src)
+}
+
+// EncodeWith is like Encode, but reuses the scratch buffer of e.
+func EncodeWith[T Integer](e *Encoder, dst []byte, src []T) []byte {
+	checkBlockSize(len(src))
+	switch len(src) {
+	case 0:
+		return dst
+	case 1:
+		dst = slices.Grow(dst, HeaderSize)[:HeaderSize]
+		EncodeHeader(dst, 1, int64(src[0]), 0)
+		return dst
+	}
+
+	// Deltas of values narrower than 64 bits cannot overflow int64, while
+	// deltas of 64-bit values and their distance from MinVal wrap around.
+	encoded := e.scratch(len(src))
+	encoded[0] = int64(src[0])
+	for i := 1; i < len(src); i++ {
+		encoded[i] = int64(src[i]) - int64(src[i-1])
+	}
+	if isRegular(encoded[1:]) {
+		return PackRegular(dst, len(src), uint64(src[0]), encoded[1], sizeOf[T]())
+	}
+	return PackBlock(dst, encoded, sizeOf[T]())
+}
+
+// Decode decodes a block encoded with Encode into dst and returns the number
+// of decoded values. Blocks of 64-bit values are decoded with DecodeInt64.
+func Decode[T Integer](dst []T, src []byte) uint16 {
+	if sizeOf[T]() == Int64SizeBytes {
+		return DecodeInt64(unsafecast.Slice[int64](dst), src)
+	}
+	if len(src) == 0 {
+		return 0
+	}
+
+	header := DecodeHeader(src)
+	if header.NumValues == 1 {
+		dst[0] = T(header.MinVal)
+		return 1
+	}
+	size := sizeOf[T]()
+	dst[0] = T(FirstValue(src[HeaderSize:], size))
+	if header.Regular {
+		Regular[T]{Start: dst[0], Interval: T(header.MinVal)}.Fill(dst[:header.NumValues])
+		return header.NumValues
+	}
+
+	var (
+		minVal = header.MinVal
+		prev   = int64(dst[0])
+		buf    [decodeChunkSize]int64
+	)
+	// Deltas are unpacked a chunk at a time into a buffer on the stack.
+	for i := 1; i < int(header.NumValues); i += decodeChunkSize {
+		deltas := buf[:min(int(header.NumValues)-i, decodeChunkSize)]
+		UnpackValues(deltas, src, header, size, i-1)
+		values := dst[i : i+len(deltas)]
+		for j, d := range deltas {
+			prev += d + minVal
+			values[j] = T(prev)
+		}
+	}
+	return header.NumValues
+}
+
+// DecodeChecked is like Decode but validates src before decoding it, see
+// DecodeInt64Checked.
+func DecodeChecked[T Integer](dst []T, src []byte) (uint16, error) {
+	if len(src) == 0 {
+		return 0, nil
+	}
+	if _, err := CheckBlock(src, len(dst), sizeOf[T]()); err != nil {
+		return 0, err
+	}
+	return Decode(dst, src), nil
+}
+
+// decodeChunkSize is the number of deltas unpacked at a time by Decode. It is
+// a multiple of 8, so that every chunk starts at a byte boundary.
+const decodeChunkSize = 256
+
+// PutFirstValue writes the low size bytes of v to dst.
+func PutFirstValue(dst []byte, v uint64, size int) {
+	switch size {
+	case 1:
+		dst[0] = byte(v)
+	case 2:
+		binary.LittleEndian.PutUint16(dst, uint16(v))
+	case 4:
+		binary.LittleEndian.PutUint32(dst, uint32(v))
+	default:
+		binary.LittleEndian.PutUint64(dst, v)
+	}
+}
+
+// FirstValue reads a value of size bytes written by PutFirstValue.
+func FirstValue(src []byte, size int) uint64 {
+	switch size {
+	case 1:
+		return uint64(src[0])
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(src))
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(src))
+	default:
+		return binary.LittleEndian.Uint64(src)
+	}
+}
+
+func sizeOf[T Integer]() int {
+	var v T
+	return int(unsafe.Sizeof(v))
+}
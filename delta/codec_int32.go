@@ -1,17 +1,8 @@
 package delta
 
-import (
-	"encoding/binary"
-	"math"
-	"slices"
-
-	"github.com/parquet-go/bitpack"
-
-	"github.com/fpetkovski/tscodec-go/alp"
-)
-
 const (
-	// Int32BlockSize is the maximum amount of values that can be encoded at once.
+	// Int32BlockSize is the maximum amount of values that can be encoded at
+	// once. Longer inputs can be encoded with EncodeInt32Blocks.
 	Int32BlockSize = 4096
 
 	// Int32SizeBytes is the size in bytes of an int32.
@@ -20,89 +11,30 @@ const (
 
 type Int32Block [Int32BlockSize]int32
 
+// EncodeInt32 encodes src into a single block, reusing the capacity of dst. It
+// panics if src holds more than Int32BlockSize values.
 func EncodeInt32(dst []byte, src []int32) []byte {
-	switch len(src) {
-	case 0:
-		return dst
-	case 1:
-		dst = slices.Grow(dst, HeaderSize)[:HeaderSize]
-		EncodeHeader(dst, 1, int64(src[0]), 0)
-		return dst
-	}
-
-	// Use int64 to avoid overflow when computing adjusted deltas
-	minVal := int64(math.MaxInt64)
-	encoded := make([]int64, len(src))
-	encoded[0] = int64(src[0])
-	for i := 1; i < len(src); i++ {
-		delta := int64(src[i]) - int64(src[i-1])
-		encoded[i] = delta
-		minVal = min(minVal, delta)
-	}
-	for i := 1; i < len(encoded); i++ {
-		encoded[i] = encoded[i] - minVal
-	}
-
-	bitWidth := 0
-	for _, v := range encoded[1:] {
-		bw := alp.CalculateBitWidth(uint64(v))
-		bitWidth = max(bitWidth, bw)
-	}
-
-	packedSize := bitpack.ByteCount(uint((len(encoded) - 1) * bitWidth))
-	totalSize := packedSize + Int32SizeBytes + HeaderSize + bitpack.PaddingInt64
-	dst = slices.Grow(dst, totalSize)[:totalSize]
-
-	EncodeHeader(dst, uint16(len(src)), minVal, uint8(bitWidth))
-
-	// Encode the first value as int32 and bitpack the rest as int64
-	binary.LittleEndian.PutUint32(dst[HeaderSize:], uint32(encoded[0]))
-	bitpack.Pack(dst[HeaderSize+Int32SizeBytes:], encoded[1:], uint(bitWidth))
+	var e Encoder
+	return e.EncodeInt32(dst, src)
+}
 
-	return dst
+// EncodeInt32 is like the EncodeInt32 function, but reuses the scratch buffer of e.
+func (e *Encoder) EncodeInt32(dst []byte, src []int32) []byte {
+	return EncodeWith(e, dst, src)
 }
 
-func DecodeInt32(dst []int32, src []byte) uint16 {
+// DecodeInt32Checked is like DecodeInt32 but validates src before decoding it,
+// see DecodeInt64Checked.
+func DecodeInt32Checked(dst []int32, src []byte) (uint16, error) {
 	if len(src) == 0 {
-		return 0
+		return 0, nil
 	}
-
-	header := DecodeHeader(src)
-
-	if header.NumValues == 1 {
-		dst[0] = int32(header.MinVal)
-		return 1
+	if _, err := CheckBlock(src, len(dst), Int32SizeBytes); err != nil {
+		return 0, err
 	}
-	dst[0] = int32(binary.LittleEndian.Uint32(src[HeaderSize:]))
-
-	// Unpack the adjusted deltas as int64 to match encoding
-	adjustedDeltas := make([]int64, header.NumValues-1)
-	bitpack.Unpack(adjustedDeltas, src[HeaderSize+Int32SizeBytes:], uint(header.BitWidth))
+	return DecodeInt32(dst, src), nil
+}
 
-	// Combine adding minVal and computing prefix sum with loop unrolling
-	minVal := header.MinVal
-	numVals := int(header.NumValues)
-	_ = dst[numVals-1] // Bounds check hint
-	i := 1
-	prev := int64(dst[0])
-	for ; i+3 < numVals; i += 4 {
-		val := adjustedDeltas[i-1] + minVal + prev
-		dst[i] = int32(val)
-		prev = val
-		val = adjustedDeltas[i] + minVal + prev
-		dst[i+1] = int32(val)
-		prev = val
-		val = adjustedDeltas[i+1] + minVal + prev
-		dst[i+2] = int32(val)
-		prev = val
-		val = adjustedDeltas[i+2] + minVal + prev
-		dst[i+3] = int32(val)
-		prev = val
-	}
-	for ; i < numVals; i++ {
-		val := adjustedDeltas[i-1] + minVal + prev
-		dst[i] = int32(val)
-		prev = val
-	}
-	return header.NumValues
+func DecodeInt32(dst []int32, src []byte) uint16 {
+	return Decode(dst, src)
 }
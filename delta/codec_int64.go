@@ -2,19 +2,20 @@ package delta
 
 import (
 	"encoding/binary"
-	"math"
-	"slices"
+	"errors"
+	"fmt"
 
 	"github.com/parquet-go/bitpack"
 
-	"github.com/fpetkovski/tscodec-go/alp"
+	"github.com/fpetkovski/tscodec-go/internal/prefixsum"
 )
 
 const (
 	// Int64SizeBytes is the size in bytes of an int64 value.
 	Int64SizeBytes = 8
 
-	// Int64BlockSize is the maximum amount of values that can be encoded at once.
+	// Int64BlockSize is the maximum amount of values that can be encoded at
+	// once. Longer inputs can be encoded with EncodeInt64Blocks.
 	Int64BlockSize = 4096
 )
 
@@ -23,51 +24,45 @@ type Int64Block [Int64BlockSize]int64
 // The HeaderSize is the size of the header of the encoded data.
 const HeaderSize = 8 + 1 + 2
 
+var (
+	// ErrCorrupt is returned when encoded data is truncated or malformed.
+	ErrCorrupt = errors.New("corrupt data")
+	// ErrShortBuffer is returned when the destination cannot hold the
+	// decoded values.
+	ErrShortBuffer = errors.New("short buffer")
+)
+
 type Header struct {
+	// MinVal is the smallest delta, or delta-of-delta in dod blocks, which
+	// is not an exception. Blocks with a single value store the value
+	// instead.
 	MinVal    int64
 	NumValues uint16
-	BitWidth  uint8
+	// BitWidth is the number of bits of each packed value, at most 64.
+	BitWidth uint8
+	// Patched is set if outliers are stored as exceptions, see PackBlock.
+	Patched bool
+	// Scaled is set if the packed values are divided by their common
+	// divisor, which follows the first value.
+	Scaled bool
+	// Regular is set if all values differ by MinVal and only the first
+	// value follows the header, see Regular.
+	Regular bool
+	// Checksum is set if the block ends with a checksum trailer, see
+	// AppendChecksum.
+	Checksum bool
 }
 
+// EncodeInt64 encodes src into a single block, reusing the capacity of dst. It
+// panics if src holds more than Int64BlockSize values.
 func EncodeInt64(dst []byte, src []int64) []byte {
-	switch len(src) {
-	case 0:
-		return dst
-	case 1:
-		dst = slices.Grow(dst, HeaderSize)[:HeaderSize]
-		EncodeHeader(dst, 1, src[0], 0)
-		return dst
-	}
-
-	minVal := int64(math.MaxInt64)
-	encoded := make([]int64, len(src))
-	encoded[0] = src[0]
-	for i := 1; i < len(src); i++ {
-		delta := src[i] - src[i-1]
-		encoded[i] = delta
-		minVal = min(minVal, delta)
-	}
-	for i := 1; i < len(encoded); i++ {
-		encoded[i] = encoded[i] - minVal
-	}
-
-	bitWidth := 0
-	for _, v := range encoded[1:] {
-		bw := alp.CalculateBitWidth(uint64(v))
-		bitWidth = max(bitWidth, bw)
-	}
-
-	packedSize := bitpack.ByteCount(uint((len(encoded) - 1) * bitWidth))
-	totalSize := packedSize + Int64SizeBytes + HeaderSize + bitpack.PaddingInt64
-	dst = slices.Grow(dst, totalSize)[:totalSize]
-
-	EncodeHeader(dst, uint16(len(src)), minVal, uint8(bitWidth))
-
-	// Encode the first value as is and bitpack the rest.
-	binary.LittleEndian.PutUint64(dst[HeaderSize:HeaderSize+Int64SizeBytes], uint64(encoded[0]))
-	bitpack.Pack(dst[HeaderSize+Int64SizeBytes:], encoded[1:], uint(bitWidth))
+	var e Encoder
+	return e.EncodeInt64(dst, src)
+}
 
-	return dst
+// EncodeInt64 is like the EncodeInt64 function, but reuses the scratch buffer of e.
+func (e *Encoder) EncodeInt64(dst []byte, src []int64) []byte {
+	return EncodeWith(e, dst, src)
 }
 
 func DecodeInt64(dst []int64, src []byte) uint16 {
@@ -82,29 +77,106 @@ func DecodeInt64(dst []int64, src []byte) uint16 {
 		return 1
 	}
 	dst[0] = int64(binary.LittleEndian.Uint64(src[HeaderSize : HeaderSize+Int64SizeBytes]))
-	bitpack.Unpack(dst[1:header.NumValues], src[HeaderSize+Int64SizeBytes:], uint(header.BitWidth))
+	if header.Regular {
+		Regular[int64]{Start: dst[0], Interval: header.MinVal}.Fill(dst[:header.NumValues])
+		return header.NumValues
+	}
+	UnpackValues(dst[1:header.NumValues], src, header, Int64SizeBytes, 0)
+
+	// Add minVal to all unpacked deltas and sum them up.
+	prefixsum.Int64(dst[1:header.NumValues], dst[0], header.MinVal)
+	return header.NumValues
+}
+
+// DecodeInt64Checked is like DecodeInt64 but validates src before decoding it.
+// It returns an error wrapping ErrCorrupt for truncated or malformed input,
+// ErrShortBuffer if dst cannot hold the encoded values and ErrChecksum if the
+// checksum trailer of the block does not match.
+func DecodeInt64Checked(dst []int64, src []byte) (uint16, error) {
+	if len(src) == 0 {
+		return 0, nil
+	}
+	if _, err := CheckBlock(src, len(dst), Int64SizeBytes); err != nil {
+		return 0, err
+	}
+	return DecodeInt64(dst, src), nil
+}
 
+// CheckBlock validates the header and checksum of an encoded block and checks
+// that src is long enough to decode it and that its values fit into dstLen
+// values. firstValueSize is the size in bytes of the first value following
+// the header.
+func CheckBlock(src []byte, dstLen int, firstValueSize int) (Header, error) {
+	if len(src) < HeaderSize {
+		return Header{}, fmt.Errorf("%w: %d bytes are too short for a header", ErrCorrupt, len(src))
+	}
+	header := DecodeHeader(src)
+	if header.Checksum {
+		var err error
+		if src, err = verifyChecksum(src); err != nil {
+			return header, err
+		}
+	}
 	numVals := int(header.NumValues)
-	// Bounds check hint.
-	_ = dst[numVals-1]
-	i := 1
-	prev := dst[0]
-	for ; i+3 < numVals; i += 4 {
-		dst[i] = dst[i] + header.MinVal + prev
-		prev = dst[i]
-		dst[i+1] = dst[i+1] + header.MinVal + prev
-		prev = dst[i+1]
-		dst[i+2] = dst[i+2] + header.MinVal + prev
-		prev = dst[i+2]
-		dst[i+3] = dst[i+3] + header.MinVal + prev
-		prev = dst[i+3]
-	}
-	prev = dst[i-1]
-	for ; i < numVals; i++ {
-		dst[i] = dst[i] + header.MinVal + prev
-		prev = dst[i]
+	switch {
+	case numVals == 0:
+		return header, fmt.Errorf("%w: block without values", ErrCorrupt)
+	case numVals > dstLen:
+		return header, fmt.Errorf("%w: %d values do not fit into %d", ErrShortBuffer, numVals, dstLen)
+	case numVals == 1:
+		return header, nil
+	case header.Regular && (header.Scaled || header.Patched || header.BitWidth != 0):
+		return header, fmt.Errorf("%w: regular block with packed values", ErrCorrupt)
+	}
+
+	offset := HeaderSize + firstValueSize
+	if header.Regular {
+		if len(src) < offset {
+			return header, fmt.Errorf("%w: %d bytes are too short for a block of %d bytes", ErrCorrupt, len(src), offset)
+		}
+		return header, nil
+	}
+	if header.Scaled {
+		if len(src) < offset+divisorSize {
+			return header, fmt.Errorf("%w: %d bytes are too short for a divisor", ErrCorrupt, len(src))
+		}
+		if d := binary.LittleEndian.Uint64(src[offset:]); d < 2 {
+			return header, fmt.Errorf("%w: divisor %d", ErrCorrupt, d)
+		}
+		offset += divisorSize
+	}
+	exceptionsOffset := offset
+	exceptions := 0
+	if header.Patched {
+		if len(src) < offset+2 {
+			return header, fmt.Errorf("%w: %d bytes are too short for an exception count", ErrCorrupt, len(src))
+		}
+		exceptions = int(binary.LittleEndian.Uint16(src[offset:]))
+		if exceptions >= numVals {
+			return header, fmt.Errorf("%w: %d exceptions in a block of %d values", ErrCorrupt, exceptions, numVals)
+		}
+		offset += 2 + exceptions*exceptionSize
+	}
+
+	packedSize := bitpack.ByteCount(uint((numVals-1)*int(header.BitWidth))) + bitpack.PaddingInt64
+	if size := offset + packedSize; len(src) < size {
+		return header, fmt.Errorf("%w: %d bytes are too short for a block of %d bytes", ErrCorrupt, len(src), size)
+	}
+	positions := src[exceptionsOffset+2:]
+	for i := range exceptions {
+		if pos := int(binary.LittleEndian.Uint16(positions[2*i:])); pos >= numVals-1 {
+			return header, fmt.Errorf("%w: exception at position %d of %d values", ErrCorrupt, pos, numVals-1)
+		}
+	}
+	return header, nil
+}
+
+// checkBlockSize panics if n values do not fit into a single block, since the
+// count in the header would silently overflow.
+func checkBlockSize(n int) {
+	if n > Int64BlockSize {
+		panic(fmt.Sprintf("delta: %d values exceed the block size of %d", n, Int64BlockSize))
 	}
-	return header.NumValues
 }
 
 func EncodeHeader(dst []byte, numVals uint16, minVal int64, bitWidth uint8) {
@@ -114,9 +186,17 @@ func EncodeHeader(dst []byte, numVals uint16, minVal int64, bitWidth uint8) {
 }
 
 func DecodeHeader(dst []byte) Header {
-	return Header{
+	header := Header{
 		MinVal:    int64(binary.LittleEndian.Uint64(dst)),
-		NumValues: binary.LittleEndian.Uint16(dst[Int64SizeBytes:]),
-		BitWidth:  dst[Int64SizeBytes+2],
+		NumValues: binary.LittleEndian.Uint16(dst[Int64SizeBytes:]) &^ (scaledFlag | regularFlag),
+		BitWidth:  dst[Int64SizeBytes+2] &^ checksumFlag,
+		Checksum:  dst[Int64SizeBytes+2]&checksumFlag != 0,
+		Scaled:    binary.LittleEndian.Uint16(dst[Int64SizeBytes:])&scaledFlag != 0,
+		Regular:   binary.LittleEndian.Uint16(dst[Int64SizeBytes:])&regularFlag != 0,
+	}
+	if header.BitWidth >= patchedBitWidth {
+		header.BitWidth -= patchedBitWidth
+		header.Patched = true
 	}
+	return header
 }
@@ -1,9 +1,16 @@
 package delta
 
 import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/bits"
 	"math/rand"
 	"slices"
 	"testing"
+
+	"github.com/parquet-go/bitpack"
+	"github.com/parquet-go/bitpack/unsafecast"
 )
 
 func TestEncode(t *testing.T) {
@@ -109,3 +116,677 @@ func FuzzInt32EncodeDecode(f *testing.F) {
 		}
 	})
 }
+
+func TestDecodeChecked(t *testing.T) {
+	encoded := EncodeInt64(nil, []int64{10, 15, 22, 31, 55, 1000})
+	corrupt := func(f func([]byte)) []byte {
+		data := slices.Clone(encoded)
+		f(data)
+		return data
+	}
+
+	tests := []struct {
+		name   string
+		src    []byte
+		dstLen int
+		err    error
+	}{
+		{name: "valid", src: encoded, dstLen: 6},
+		{name: "empty", src: nil, dstLen: 0},
+		{name: "single value", src: EncodeInt64(nil, []int64{3}), dstLen: 1},
+		{name: "short header", src: encoded[:HeaderSize-1], dstLen: 6, err: ErrCorrupt},
+		{name: "truncated", src: encoded[:HeaderSize+Int64SizeBytes], dstLen: 6, err: ErrCorrupt},
+		{name: "short dst", src: encoded, dstLen: 5, err: ErrShortBuffer},
+		{name: "no values", src: corrupt(func(b []byte) { b[8], b[9] = 0, 0 }), dstLen: 6, err: ErrCorrupt},
+		{name: "too many values", src: corrupt(func(b []byte) { b[9] = 1 }), dstLen: Int64BlockSize, err: ErrCorrupt},
+		{name: "patched", src: corrupt(func(b []byte) { b[10] = patchedBitWidth + 62 }), dstLen: 6, err: ErrCorrupt},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dst := make([]int64, tc.dstLen)
+			n, err := DecodeInt64Checked(dst, tc.src)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			if err != nil {
+				return
+			}
+			want := make([]int64, tc.dstLen)
+			if m := DecodeInt64(want, tc.src); !slices.Equal(dst[:n], want[:m]) {
+				t.Fatalf("Slices are not equal: got: [%v] want: [%v]", dst[:n], want[:m])
+			}
+		})
+	}
+}
+
+func FuzzDecodeChecked(f *testing.F) {
+	f.Add(EncodeInt64(nil, []int64{10, 15, 22, 31, 55, 1000}))
+	f.Add(EncodeInt32(nil, []int32{-5, 7, 1 << 30}))
+	f.Add(EncodeInt64(nil, []int64{3}))
+	f.Add(EncodeInt64(nil, []int64{10, 20, 30, 40}))
+	f.Add(EncodeInt64(nil, []int64{10, 20, 30, 1 << 40, 50, 60, 70, 80, 90, 100}))
+	f.Add(EncodeInt64(nil, []int64{0, 1000, 3000, 4000, 7000, 9000, 10000, 14000, 15000, 17000, 18000, 23000, 24000, 26000, 27000, 31000, 34000}))
+
+	f.Fuzz(func(t *testing.T, src []byte) {
+		var (
+			block64 Int64Block
+			block32 Int32Block
+		)
+		_, _ = DecodeInt64Checked(block64[:], src)
+		_, _ = DecodeInt32Checked(block32[:], src)
+		_, _ = DecodeInt64Checked(block64[:3], src)
+		_, _ = DecodeInt64BlocksChecked(nil, src)
+		_, _ = DecodeInt32BlocksChecked(nil, src)
+	})
+}
+
+func TestBlocks(t *testing.T) {
+	for _, size := range []int{0, 1, 2, Int64BlockSize, Int64BlockSize + 1, 70000} {
+		src := make([]int64, size)
+		val := int64(1700000000000)
+		for i := range src {
+			val += 15000 + rand.Int63n(100)
+			src[i] = val
+		}
+		encoded := EncodeInt64Blocks(make([]byte, 0, 64), src)
+
+		decoded := DecodeInt64Blocks(nil, encoded)
+		if !slices.Equal(src, decoded) {
+			t.Fatalf("size %d: Slices are not equal", size)
+		}
+		decoded, err := DecodeInt64BlocksChecked(decoded, encoded)
+		if err != nil {
+			t.Fatalf("size %d: unexpected error: %v", size, err)
+		}
+		if !slices.Equal(src, decoded) {
+			t.Fatalf("size %d: Slices are not equal", size)
+		}
+
+		vals := make([]int32, size)
+		for i := range vals {
+			vals[i] = int32(src[i] % 1000000)
+		}
+		decoded32, err := DecodeInt32BlocksChecked(nil, EncodeInt32Blocks(nil, vals))
+		if err != nil {
+			t.Fatalf("size %d: unexpected error: %v", size, err)
+		}
+		if !slices.Equal(vals, decoded32) {
+			t.Fatalf("size %d: Slices are not equal", size)
+		}
+	}
+}
+
+func TestBlocksChecked(t *testing.T) {
+	src := make([]int64, Int64BlockSize+10)
+	for i := range src {
+		src[i] = int64(i * i)
+	}
+	encoded := EncodeInt64Blocks(nil, src)
+	corrupt := func(f func([]byte)) []byte {
+		data := slices.Clone(encoded)
+		f(data)
+		return data
+	}
+
+	tests := []struct {
+		name string
+		src  []byte
+	}{
+		{name: "short header", src: encoded[:BlocksHeaderSize-1]},
+		{name: "truncated", src: encoded[:len(encoded)-1]},
+		{name: "count", src: corrupt(func(b []byte) { b[0]++ })},
+		{name: "block length", src: corrupt(func(b []byte) { b[BlocksHeaderSize]++ })},
+		{name: "block", src: corrupt(func(b []byte) { b[BlocksHeaderSize+4+10] = 65 })},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := DecodeInt64BlocksChecked(nil, tc.src); !errors.Is(err, ErrCorrupt) {
+				t.Fatalf("expected ErrCorrupt, got %v", err)
+			}
+		})
+	}
+}
+
+func TestBlockSizeLimit(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	EncodeInt64(nil, make([]int64, Int64BlockSize+1))
+}
+
+func TestChecksum(t *testing.T) {
+	src := []int64{10, 15, 22, 31, 55, 1000}
+	encoded := AppendChecksum(EncodeInt64(nil, src))
+	if !DecodeHeader(encoded).Checksum {
+		t.Fatal("expected checksum flag to be set")
+	}
+
+	var decoded Int64Block
+	n, err := DecodeInt64Checked(decoded[:], encoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(src, decoded[:n]) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], src)
+	}
+	n = DecodeInt64(decoded[:], encoded)
+	if !slices.Equal(src, decoded[:n]) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], src)
+	}
+
+	for pos := range encoded {
+		corrupted := slices.Clone(encoded)
+		corrupted[pos] ^= 0x01
+		if _, err := DecodeInt64Checked(decoded[:], corrupted); !errors.Is(err, ErrChecksum) {
+			t.Fatalf("expected ErrChecksum for flipped byte %d, got %v", pos, err)
+		}
+	}
+
+	single := AppendChecksum(EncodeInt32(nil, []int32{-7}))
+	var decoded32 Int32Block
+	if n, err := DecodeInt32Checked(decoded32[:], single); err != nil || n != 1 || decoded32[0] != -7 {
+		t.Fatalf("unexpected result: %d %v %v", n, decoded32[0], err)
+	}
+}
+
+func TestEncoderAllocs(t *testing.T) {
+	gen := rand.New(rand.NewSource(25))
+	// Timestamps scraped every 15s, offset by up to jitter multiples of step.
+	timestamps := func(jitter, step int64) []int64 {
+		src := make([]int64, 120)
+		for i := range src {
+			src[i] = 1_700_000_000_000 + int64(i)*15_000 + gen.Int63n(jitter+1)*step
+		}
+		return src
+	}
+	missed := timestamps(100, 1)
+	for i := 60; i < len(missed); i++ {
+		missed[i] += 15_000
+	}
+	tests := []struct {
+		name string
+		src  []int64
+	}{
+		{
+			name: "jittered timestamps",
+			src:  timestamps(100, 1),
+		},
+		{
+			name: "regular timestamps",
+			src:  timestamps(0, 1),
+		},
+		{
+			name: "scaled timestamps",
+			src:  timestamps(3, 1000),
+		},
+		{
+			name: "patched timestamps",
+			src:  missed,
+		},
+		{
+			name: "empty source",
+			src:  nil,
+		},
+	}
+
+	var (
+		e   Encoder
+		dst []byte
+	)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Values relative to the first timestamp fit in int32.
+			vals := make([]int32, len(tt.src))
+			for i := range vals {
+				vals[i] = int32(tt.src[i] - 1_700_000_000_000)
+			}
+			// dst is reused with the blocks of previous cases beyond its length.
+			dst = e.EncodeInt64(dst[:0], tt.src)
+			allocs := testing.AllocsPerRun(10, func() {
+				dst = e.EncodeInt32(dst[:0], vals)
+				dst = e.EncodeInt64(dst[:0], tt.src)
+			})
+			if allocs != 0 {
+				t.Fatalf("expected no allocations, got %v", allocs)
+			}
+
+			if want := EncodeInt64(nil, tt.src); !slices.Equal(dst, want) {
+				t.Fatalf("encoder output differs from EncodeInt64")
+			}
+			var decoded Int64Block
+			n := DecodeInt64(decoded[:], dst)
+			if !slices.Equal(tt.src, decoded[:n]) {
+				t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], tt.src)
+			}
+			dst = e.EncodeInt32(dst[:0], vals)
+			var decoded32 Int32Block
+			n = DecodeInt32(decoded32[:], dst)
+			if !slices.Equal(vals, decoded32[:n]) {
+				t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded32[:n], vals)
+			}
+		})
+	}
+}
+
+func TestGeneric(t *testing.T) {
+	gen := rand.New(rand.NewSource(21))
+	t.Run("int8", func(t *testing.T) { testGeneric[int8](t, gen) })
+	t.Run("uint8", func(t *testing.T) { testGeneric[uint8](t, gen) })
+	t.Run("int16", func(t *testing.T) { testGeneric[int16](t, gen) })
+	t.Run("uint16", func(t *testing.T) { testGeneric[uint16](t, gen) })
+	t.Run("int32", func(t *testing.T) { testGeneric[int32](t, gen) })
+	t.Run("uint32", func(t *testing.T) { testGeneric[uint32](t, gen) })
+	t.Run("int64", func(t *testing.T) { testGeneric[int64](t, gen) })
+	t.Run("uint64", func(t *testing.T) { testGeneric[uint64](t, gen) })
+	t.Run("uint", func(t *testing.T) { testGeneric[uint](t, gen) })
+
+	// Blocks of int64 and int32 values are the ones of the typed encoders.
+	src := make([]int64, 1000)
+	for i := range src {
+		src[i] = gen.Int63n(1 << 40)
+	}
+	if got, want := Encode(nil, src), EncodeInt64(nil, src); !slices.Equal(got, want) {
+		t.Fatalf("Encode differs from EncodeInt64")
+	}
+	src32 := make([]int32, 1000)
+	for i := range src32 {
+		src32[i] = int32(gen.Uint32())
+	}
+	if got, want := Encode(nil, src32), EncodeInt32(nil, src32); !slices.Equal(got, want) {
+		t.Fatalf("Encode differs from EncodeInt32")
+	}
+}
+
+func testGeneric[T Integer](t *testing.T, gen *rand.Rand) {
+	var (
+		zero   T
+		minVal = ^zero
+		maxVal = ^zero
+	)
+	if minVal < 0 {
+		// Signed types have the sign bit set in their minimum.
+		maxVal = T(uint64(maxVal) >> 1)
+		minVal = ^maxVal
+	} else {
+		minVal = 0
+	}
+	datasets := [][]T{
+		nil,
+		{maxVal},
+		{minVal, maxVal, minVal, 0, maxVal},
+		{1, 2, 3, 5, 8, 13, 21, 34, 55, 89},
+	}
+	for _, n := range []int{7, 1000, Int64BlockSize} {
+		values := make([]T, n)
+		for i := range values {
+			values[i] = T(gen.Uint64())
+		}
+		datasets = append(datasets, values)
+	}
+
+	var e Encoder
+	for _, src := range datasets {
+		encoded := EncodeWith(&e, nil, src)
+
+		decoded := make([]T, len(src))
+		if n := Decode(decoded, encoded); !slices.Equal(decoded[:n], src) {
+			t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], src)
+		}
+		n, err := DecodeChecked(decoded, AppendChecksum(encoded))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(decoded[:n], src) {
+			t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], src)
+		}
+	}
+
+	var (
+		src     = datasets[len(datasets)-1]
+		dst     = EncodeWith(&e, nil, src)
+		decoded = make([]T, len(src))
+	)
+	allocs := testing.AllocsPerRun(10, func() {
+		dst = EncodeWith(&e, dst[:0], src)
+		Decode(decoded, dst)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+
+	src = slices.Repeat(src, 3)
+	encoded := EncodeBlocks(nil, src, Encode[T])
+	if decoded := DecodeBlocks(nil, encoded, Decode[T]); !slices.Equal(decoded, src) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded, src)
+	}
+	decoded, err := DecodeBlocksChecked(nil, encoded, DecodeChecked[T])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded, src) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded, src)
+	}
+}
+
+// fullRangeSeeds are sequences of 64-bit values which span the whole range,
+// see the package documentation.
+var fullRangeSeeds = [][]uint64{
+	{math.MaxInt64 + 1, math.MaxInt64},
+	{math.MaxInt64, math.MaxInt64 + 1, math.MaxInt64, math.MaxInt64 + 1},
+	{0, math.MaxUint64, 0, math.MaxUint64, 1},
+	{math.MaxInt64 - 1, math.MaxInt64, math.MaxInt64 + 1, math.MaxInt64 + 2},
+	{0, math.MaxInt64 + 1, math.MaxUint64, 1, math.MaxInt64, math.MaxInt64 + 2, math.MaxUint64 - 1},
+	{0x9e3779b97f4a7c15, 0x3c6ef372fe94f82a, 0xdaa66d2c7ddf743f, 0x78dde6e5fd29f054},
+}
+
+func TestFullRange(t *testing.T) {
+	for _, values := range fullRangeSeeds {
+		testFullRange(t, values)
+	}
+
+	// Deltas spread over the whole range, from math.MinInt64 to
+	// math.MaxInt64, are packed with 64 bits.
+	values := []uint64{0, math.MaxInt64 + 1, math.MaxUint64}
+	gen := rand.New(rand.NewSource(22))
+	for range 61 {
+		values = append(values, gen.Uint64())
+	}
+	if header := DecodeHeader(Encode(nil, values)); header.BitWidth != 64 || header.Patched {
+		t.Fatalf("expected a bit width of 64, got %d", header.BitWidth)
+	}
+	testFullRange(t, values)
+}
+
+// FuzzFullRange round-trips arbitrary 64-bit values, read from data as
+// little-endian uint64s. The corpus in testdata holds boundary values.
+func FuzzFullRange(f *testing.F) {
+	for _, values := range fullRangeSeeds {
+		var data []byte
+		for _, v := range values {
+			data = binary.LittleEndian.AppendUint64(data, v)
+		}
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		values := make([]uint64, min(len(data)/8, Int64BlockSize))
+		for i := range values {
+			values[i] = binary.LittleEndian.Uint64(data[i*8:])
+		}
+		testFullRange(t, values)
+	})
+}
+
+// testFullRange checks that values round-trip as uint64 and int64, and that
+// both encode to the same block.
+func testFullRange(t *testing.T, values []uint64) {
+	encoded := Encode(nil, values)
+	ints := unsafecast.Slice[int64](values)
+	if want := EncodeInt64(nil, ints); !slices.Equal(encoded, want) {
+		t.Fatalf("uint64 and int64 blocks differ")
+	}
+
+	decoded := make([]uint64, len(values))
+	n, err := DecodeChecked(decoded, encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded[:n], values) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], values)
+	}
+	decodedInts := make([]int64, len(ints))
+	if n := DecodeInt64(decodedInts, encoded); !slices.Equal(decodedInts[:n], ints) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decodedInts[:n], ints)
+	}
+}
+
+func TestPatched(t *testing.T) {
+	// Timestamps scraped every 15s with jitter and a single missed scrape.
+	timestamps := make([]int64, 120)
+	gen := rand.New(rand.NewSource(23))
+	ts := int64(1_700_000_000_000)
+	for i := range timestamps {
+		ts += 15_000 + gen.Int63n(8)
+		if i == 60 {
+			ts += 15_000
+		}
+		timestamps[i] = ts
+	}
+	// Values with both positive and negative outliers.
+	outliers := make([]int64, Int64BlockSize)
+	for i := range outliers {
+		outliers[i] = int64(i)
+	}
+	outliers[3] = math.MinInt64
+	outliers[700] = math.MaxInt64
+	outliers[701] = -1 << 40
+
+	for _, values := range [][]int64{timestamps, outliers} {
+		encoded := EncodeInt64(nil, values)
+		header := DecodeHeader(encoded)
+		if !header.Patched {
+			t.Fatalf("expected a patched block")
+		}
+		deltas := make([]int64, len(values)-1)
+		for i := range deltas {
+			deltas[i] = values[i+1] - values[i]
+		}
+		bitWidth := bits.Len64(uint64(slices.Max(deltas) - slices.Min(deltas)))
+		if plain := HeaderSize + Int64SizeBytes + bitpack.ByteCount(uint(len(deltas)*bitWidth)); len(encoded) >= plain {
+			t.Fatalf("patched block of %d bytes is not smaller than %d bytes", len(encoded), plain)
+		}
+		decoded := make([]int64, len(values))
+		n, err := DecodeInt64Checked(decoded, encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(decoded[:n], values) {
+			t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], values)
+		}
+	}
+
+	// Narrow types are decoded in chunks, with exceptions in several of them.
+	narrow := make([]int16, 1000)
+	for i := range narrow {
+		narrow[i] = int16(i % 7)
+	}
+	for _, i := range []int{1, 255, 256, 511, 999} {
+		narrow[i] = math.MinInt16 + int16(i)
+	}
+	encoded := Encode(nil, narrow)
+	if !DecodeHeader(encoded).Patched {
+		t.Fatalf("expected a patched block")
+	}
+	decoded := make([]int16, len(narrow))
+	n, err := DecodeChecked(decoded, encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded[:n], narrow) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], narrow)
+	}
+}
+
+func TestPatchedChecked(t *testing.T) {
+	values := []int64{10, 20, 30, 1 << 40, 50, 60, 70, 80, 90, 100}
+	encoded := EncodeInt64(nil, values)
+	if !DecodeHeader(encoded).Patched {
+		t.Fatalf("expected a patched block")
+	}
+	exceptions := HeaderSize + Int64SizeBytes
+	corrupt := func(f func([]byte)) []byte {
+		data := slices.Clone(encoded)
+		f(data)
+		return data
+	}
+
+	tests := []struct {
+		name string
+		src  []byte
+	}{
+		{name: "missing exception count", src: encoded[:exceptions+1]},
+		{name: "truncated exceptions", src: encoded[:exceptions+2+exceptionSize-1]},
+		{name: "too many exceptions", src: corrupt(func(b []byte) { binary.LittleEndian.PutUint16(b[exceptions:], uint16(len(values))) })},
+		{name: "exception position", src: corrupt(func(b []byte) { binary.LittleEndian.PutUint16(b[exceptions+2:], uint16(len(values)-1)) })},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var block Int64Block
+			if _, err := DecodeInt64Checked(block[:], tc.src); !errors.Is(err, ErrCorrupt) {
+				t.Fatalf("expected ErrCorrupt, got %v", err)
+			}
+		})
+	}
+}
+
+func TestScaled(t *testing.T) {
+	gen := rand.New(rand.NewSource(24))
+	// Millisecond timestamps of whole seconds, nanosecond timestamps of
+	// whole milliseconds with a missed scrape, and counters incrementing in
+	// fixed steps around the limits of int64.
+	millis := make([]int64, 1000)
+	nanos := make([]int64, Int64BlockSize)
+	steps := make([]int64, 300)
+	for i := range millis {
+		millis[i] = 1_700_000_000_000 + int64(i)*15_000 + gen.Int63n(3)*1000
+	}
+	for i := range nanos {
+		nanos[i] = 1_700_000_000_000_000_000 + int64(i)*15_000_000_000 + gen.Int63n(50)*1_000_000
+	}
+	nanos[2000] += 15_000_000_000
+	steps[0] = math.MaxInt64 - 1000
+	for i := 1; i < len(steps); i++ {
+		steps[i] = steps[i-1] + 6*(1+gen.Int63n(3))
+	}
+
+	for _, tc := range []struct {
+		values   []int64
+		bitWidth uint8
+	}{
+		{values: millis, bitWidth: 3},
+		{values: nanos, bitWidth: 7},
+		{values: steps, bitWidth: 2},
+	} {
+		encoded := EncodeInt64(nil, tc.values)
+		if header := DecodeHeader(encoded); !header.Scaled || header.BitWidth != tc.bitWidth {
+			t.Fatalf("expected a scaled block with a bit width of %d, got %d", tc.bitWidth, header.BitWidth)
+		}
+		decoded := make([]int64, len(tc.values))
+		n, err := DecodeInt64Checked(decoded, encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(decoded[:n], tc.values) {
+			t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], tc.values)
+		}
+	}
+
+	// Narrow types are decoded in chunks.
+	narrow := make([]uint16, 1000)
+	for i := range narrow {
+		narrow[i] = uint16(6 * gen.Intn(1000))
+	}
+	encoded := Encode(nil, narrow)
+	if !DecodeHeader(encoded).Scaled {
+		t.Fatalf("expected a scaled block")
+	}
+	decoded := make([]uint16, len(narrow))
+	n, err := DecodeChecked(decoded, encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded[:n], narrow) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], narrow)
+	}
+
+	// Blocks are not scaled if the divisor takes more space than it saves.
+	if DecodeHeader(EncodeInt64(nil, []int64{0, 1000, 3000})).Scaled {
+		t.Fatalf("expected a block which is not scaled")
+	}
+}
+
+func TestScaledChecked(t *testing.T) {
+	values := make([]int64, 100)
+	for i := range values {
+		values[i] = int64(i*i) * 1000
+	}
+	encoded := EncodeInt64(nil, values)
+	if !DecodeHeader(encoded).Scaled {
+		t.Fatalf("expected a scaled block")
+	}
+	divisor := HeaderSize + Int64SizeBytes
+	for _, d := range []uint64{0, 1} {
+		src := slices.Clone(encoded)
+		binary.LittleEndian.PutUint64(src[divisor:], d)
+		var block Int64Block
+		if _, err := DecodeInt64Checked(block[:], src); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("expected ErrCorrupt for divisor %d, got %v", d, err)
+		}
+	}
+	var block Int64Block
+	if _, err := DecodeInt64Checked(block[:], encoded[:divisor+divisorSize-1]); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+}
+
+func TestDivisor(t *testing.T) {
+	gen := rand.New(rand.NewSource(24))
+	for _, d := range []uint64{1, 2, 3, 6, 1000, 1_000_000, 1 << 40, 3 << 62, math.MaxUint64} {
+		div := newDivisor(d)
+		for range 1000 {
+			q := gen.Uint64() / d
+			if x := q * d; !div.divides(x) || div.quotient(x) != q {
+				t.Fatalf("%d is a multiple of %d", x, d)
+			}
+			if d == 1 {
+				continue
+			}
+			if x := q*d + 1 + gen.Uint64()%(d-1); x > q*d && div.divides(x) {
+				t.Fatalf("%d is not a multiple of %d", x, d)
+			}
+		}
+	}
+	if gcd := commonDivisor([]int64{-12, 18, 0, 30}, -12, 42); gcd != 6 {
+		t.Fatalf("expected a common divisor of 6, got %d", gcd)
+	}
+}
+
+func TestRegular(t *testing.T) {
+	values := []int64{-40, -20, 0, 20, 40, 60}
+	encoded := EncodeInt64(nil, values)
+	if len(encoded) != HeaderSize+Int64SizeBytes || !DecodeHeader(encoded).Regular {
+		t.Fatalf("expected a regular block of %d bytes, got %d", HeaderSize+Int64SizeBytes, len(encoded))
+	}
+	decoded := make([]int64, len(values))
+	n, err := DecodeInt64Checked(decoded, encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded[:n], values) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], values)
+	}
+
+	// The distance of a value from the start may overflow T.
+	narrow := []int8{-120, -60, 0, 60, 120}
+	r, ok := DecodeRegular[int8](Encode(nil, narrow))
+	if !ok {
+		t.Fatalf("expected a regular block")
+	}
+	for _, v := range []int8{math.MinInt8, -120, -1, 0, 1, 119, 120, math.MaxInt8} {
+		wantIndex, wantFound := slices.BinarySearch(narrow, v)
+		if i, found := r.Search(v); i != wantIndex || found != wantFound {
+			t.Fatalf("expected %d, %v for %d, got %d, %v", wantIndex, wantFound, v, i, found)
+		}
+	}
+	filled := make([]int8, len(narrow))
+	r.Fill(filled)
+	if !slices.Equal(filled, narrow) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", filled, narrow)
+	}
+
+	// Constant series are regular with an interval of 0.
+	if r, ok := DecodeRegular[uint32](Encode(nil, []uint32{7, 7, 7})); !ok || r.Interval != 0 || r.At(2) != 7 {
+		t.Fatalf("expected a constant series, got %+v", r)
+	}
+}
@@ -0,0 +1,271 @@
+package delta
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"slices"
+
+	"github.com/parquet-go/bitpack"
+)
+
+// A block packs all values but the first one with the bit width of the
+// largest of them. If a few outliers, such as the delta of a missed scrape,
+// need many more bits than the other values, the block is patched instead:
+// the outliers are stored as exceptions and the remaining values are packed
+// with a smaller bit width. A patched block follows the first value with
+//
+//	exception count (uint16)
+//	exception positions (uint16 each)
+//	exception values (uint64 each)
+//	packed values, holding zero at the positions of exceptions
+//
+// Values and exceptions are stored relative to MinVal of the header, which is
+// the smallest value that is not an exception. Patched blocks flag their
+// header by storing patchedBitWidth plus the bit width of the packed values.
+
+const (
+	// patchedBitWidth is added to the bit width of patched blocks. Widths of
+	// other blocks never exceed 64.
+	patchedBitWidth = 65
+	// maxPatchedBitWidth is the largest bit width of a patched block, which
+	// leaves the high bit of the bit width byte for checksumFlag.
+	maxPatchedBitWidth = checksumFlag - 1 - patchedBitWidth
+
+	// exceptionSize is the size in bytes of an exception.
+	exceptionSize = 2 + 8
+
+	// medianSamples is the number of values sampled to find a value within
+	// the bulk of a block, which outliers are detected relative to.
+	medianSamples = 9
+)
+
+// PackBlock writes a block holding encoded to dst, reusing its capacity. The
+// first value is stored with size bytes and the other values are packed,
+// patching outliers and scaling the values by their common divisor if this
+// makes the block smaller. The values are overwritten. It is shared with the
+// dod package.
+func PackBlock(dst []byte, encoded []int64, size int) []byte {
+	values := encoded[1:]
+	base, span, exceptions := choosePatch(values)
+
+	var (
+		bitWidth = bits.Len64(span)
+		numVals  = uint16(len(encoded))
+		gcd      = uint64(1)
+	)
+	if exceptions == 0 {
+		bitWidth = max(bitWidth, 1)
+	}
+	// Scaling pays off if the packed values shrink by more than the size of
+	// the divisor, which requires them to shrink to at least one bit less.
+	if bitpack.ByteCount(uint(len(values)*(bitWidth-1)))+divisorSize < bitpack.ByteCount(uint(len(values)*bitWidth)) {
+		gcd = commonDivisor(values, base, span)
+	}
+	if gcd > 1 {
+		scaledWidth := max(bits.Len64(span/gcd), 1)
+		if bitpack.ByteCount(uint(len(values)*scaledWidth))+divisorSize < bitpack.ByteCount(uint(len(values)*bitWidth)) {
+			numVals |= scaledFlag
+			bitWidth = scaledWidth
+		} else {
+			gcd = 1
+		}
+	}
+
+	offset := HeaderSize + size
+	if gcd > 1 {
+		offset += divisorSize
+	}
+	exceptionsOffset := offset
+	if exceptions > 0 {
+		offset += 2 + exceptions*exceptionSize
+	}
+	packedSize := bitpack.ByteCount(uint(len(values) * bitWidth))
+	totalSize := offset + packedSize + bitpack.PaddingInt64
+	dst = slices.Grow(dst, totalSize)[:totalSize]
+
+	PutFirstValue(dst[HeaderSize:], uint64(encoded[0]), size)
+	if gcd > 1 {
+		binary.LittleEndian.PutUint64(dst[HeaderSize+size:], gcd)
+	}
+	d := newDivisor(gcd)
+	switch {
+	case exceptions == 0 && gcd == 1:
+		EncodeHeader(dst, numVals, base, uint8(bitWidth))
+		for i, v := range values {
+			values[i] = v - base
+		}
+	case exceptions == 0:
+		EncodeHeader(dst, numVals, base, uint8(bitWidth))
+		for i, v := range values {
+			values[i] = int64(d.quotient(uint64(v - base)))
+		}
+	default:
+		EncodeHeader(dst, numVals, base, uint8(patchedBitWidth+bitWidth))
+		var (
+			positions = dst[exceptionsOffset+2:]
+			patches   = positions[2*exceptions:]
+			n         = 0
+		)
+		binary.LittleEndian.PutUint16(dst[exceptionsOffset:], uint16(exceptions))
+		for i, v := range values {
+			delta := uint64(v - base)
+			if delta <= span {
+				values[i] = int64(d.quotient(delta))
+				continue
+			}
+			binary.LittleEndian.PutUint16(positions[2*n:], uint16(i))
+			binary.LittleEndian.PutUint64(patches[8*n:], delta)
+			values[i] = 0
+			n++
+		}
+	}
+	// Pack does not write the padding, which may hold stale bytes of dst.
+	bitpack.Pack(dst[offset:], values, uint(bitWidth))
+	clear(dst[offset+packedSize:])
+	return dst
+}
+
+// choosePatch returns the MinVal and the span of the packed values which
+// minimize the size of a block of values, and the number of values outside of
+// the span, which are patched. Outliers
+// are detected by the bit length of their distance from the median of a
+// sample of the values, which falls within the bulk of the values unless most
+// of them are outliers.
+func choosePatch(values []int64) (base int64, span uint64, exceptions int) {
+	var samples [medianSamples]int64
+	for i := range samples {
+		samples[i] = values[(2*i+1)*len(values)/(2*medianSamples)]
+	}
+	slices.Sort(samples[:])
+	ref := samples[medianSamples/2]
+
+	// Distances below ref are counted from index 65 on. Consecutive values
+	// are counted in separate histograms, to avoid a dependency between
+	// values of the same bit length.
+	var (
+		counts          [4][2 * 65]int
+		lowest, highest = values[0], values[0]
+	)
+	for i, v := range values {
+		neg := b2i(v < ref)
+		mask := -uint64(neg)
+		far := (uint64(v-ref) ^ mask) - mask
+		counts[i&3][bits.Len64(far)+65*neg]++
+		lowest, highest = min(lowest, v), max(highest, v)
+	}
+	var above, below [65]int
+	for i := range above {
+		for j := range counts {
+			above[i] += counts[j][i]
+			below[i] += counts[j][65+i]
+		}
+	}
+
+	// Values whose distance below ref has a bit length of at most lo, and
+	// whose distance above it has a bit length of at most hi, are packed. The
+	// others are exceptions. The bit width of the packed values is bounded by
+	// the largest distances of these bit lengths.
+	var (
+		fullSpan       = uint64(highest - lowest)
+		bestSize       = -1
+		bestLo, bestHi int
+		bestExceptions int
+		excluded       = len(values)
+	)
+	for lo := range below {
+		if lo > 0 && below[lo] == 0 {
+			continue
+		}
+		excluded -= below[lo]
+		belowExcluded := excluded
+		for hi := range above {
+			if above[hi] == 0 {
+				continue
+			}
+			excluded -= above[hi]
+
+			var size int
+			switch {
+			case excluded == 0:
+				size = bitpack.ByteCount(uint(len(values) * max(bits.Len64(fullSpan), 1)))
+			case lo <= maxPatchedBitWidth && hi <= maxPatchedBitWidth:
+				w := bits.Len64(min(fullSpan, 1<<lo-1+1<<hi-1))
+				if w > maxPatchedBitWidth {
+					continue
+				}
+				size = 2 + excluded*exceptionSize + bitpack.ByteCount(uint(len(values)*w))
+			default:
+				continue
+			}
+			// Blocks without exceptions are preferred if the size is equal.
+			if bestSize < 0 || size < bestSize || size == bestSize && excluded == 0 {
+				bestSize, bestLo, bestHi, bestExceptions = size, lo, hi, excluded
+			}
+		}
+		excluded = belowExcluded
+	}
+	if bestExceptions == 0 {
+		return lowest, fullSpan, 0
+	}
+
+	// The span is computed from the values which are actually packed, which
+	// are exactly the values within it.
+	base, highest = ref, ref
+	for _, v := range values {
+		if v < ref && uint64(ref-v)>>bestLo == 0 {
+			base = min(base, v)
+		} else if v > ref && uint64(v-ref)>>bestHi == 0 {
+			highest = max(highest, v)
+		}
+	}
+	return base, uint64(highest - base), bestExceptions
+}
+
+func b2i(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// UnpackValues unpacks the values of a block with indexes [offset,
+// offset+len(dst)) into dst, scales them and patches in its exceptions. The
+// values are
+// relative to MinVal of the header. The first value of the block takes size
+// bytes, and offset must be a multiple of 8. It is shared with the dod
+// package.
+func UnpackValues(dst []int64, src []byte, header Header, size, offset int) {
+	var (
+		packed     = src[HeaderSize+size:]
+		exceptions []byte
+		count      int
+	)
+	var scale int64 = 1
+	if header.Scaled {
+		scale = int64(binary.LittleEndian.Uint64(packed))
+		packed = packed[divisorSize:]
+	}
+	if header.Patched {
+		count = int(binary.LittleEndian.Uint16(packed))
+		exceptions = packed[2:]
+		packed = packed[2+count*exceptionSize:]
+	}
+
+	bitWidth := uint(header.BitWidth)
+	if bitWidth > 0 {
+		bitpack.Unpack(dst, packed[uint(offset)*bitWidth/8:], bitWidth)
+	} else {
+		clear(dst)
+	}
+	if scale != 1 {
+		for i := range dst {
+			dst[i] *= scale
+		}
+	}
+	for i := range count {
+		pos := int(binary.LittleEndian.Uint16(exceptions[2*i:])) - offset
+		if pos >= 0 && pos < len(dst) {
+			dst[pos] = int64(binary.LittleEndian.Uint64(exceptions[2*count+8*i:]))
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package delta
+
+import "slices"
+
+// A block of values which all differ by the same interval, such as the
+// timestamps of a series scraped without jitter, is regular: its header holds
+// the interval as MinVal and sets regularFlag in the value count, and only the
+// first value follows. Nothing is packed, so the values are decoded with a
+// single addition each and any of them can be computed directly.
+
+// regularFlag is set in the value count of the header of regular blocks.
+const regularFlag = 0x4000
+
+// Regular is a series of Len values starting at Start, each of which differs
+// from the previous one by Interval. Like all arithmetic of the codecs, the
+// values wrap around modulo the range of T.
+type Regular[T Integer] struct {
+	Start    T
+	Interval T
+	Len      int
+}
+
+// DecodeRegular returns the series of a regular block of values of type T,
+// and false if src is not a regular block. It does not verify the checksum
+// of the block.
+func DecodeRegular[T Integer](src []byte) (Regular[T], bool) {
+	size := sizeOf[T]()
+	if len(src) < HeaderSize+size {
+		return Regular[T]{}, false
+	}
+	header := DecodeHeader(src)
+	if !header.Regular {
+		return Regular[T]{}, false
+	}
+	return Regular[T]{
+		Start:    T(FirstValue(src[HeaderSize:], size)),
+		Interval: T(header.MinVal),
+		Len:      int(header.NumValues),
+	}, true
+}
+
+// At returns the value with index i, which must be in [0, Len).
+func (r Regular[T]) At(i int) T {
+	return r.Start + T(i)*r.Interval
+}
+
+// Fill writes the first len(dst) values of the series to dst.
+func (r Regular[T]) Fill(dst []T) {
+	v := r.Start
+	for i := range dst {
+		dst[i] = v
+		v += r.Interval
+	}
+}
+
+// Search returns the index of the first value which is not smaller than v,
+// and whether it equals v, like slices.BinarySearch. The values must not
+// decrease, which holds for timestamps with a non-negative Interval.
+func (r Regular[T]) Search(v T) (int, bool) {
+	if r.Len == 0 || v <= r.Start {
+		return 0, r.Len > 0 && v == r.Start
+	}
+	if v > r.At(r.Len-1) {
+		return r.Len, false
+	}
+	// Start < v <= At(Len-1), so Interval is positive. The distance from
+	// Start is computed in uint64, which cannot overflow.
+	var (
+		distance = uint64(v) - uint64(r.Start)
+		interval = uint64(r.Interval)
+		i        = int(distance / interval)
+	)
+	if distance%interval == 0 {
+		return i, true
+	}
+	return i + 1, false
+}
+
+// isRegular reports whether all values are equal to the first one.
+func isRegular(values []int64) bool {
+	for _, v := range values[1:] {
+		if v != values[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// PackRegular writes a regular block of n values to dst, reusing its
+// capacity. The first value is stored with size bytes. It is shared with the
+// dod package.
+func PackRegular(dst []byte, n int, first uint64, interval int64, size int) []byte {
+	dst = slices.Grow(dst, HeaderSize+size)[:HeaderSize+size]
+	EncodeHeader(dst, uint16(n)|regularFlag, interval, 0)
+	PutFirstValue(dst[HeaderSize:], first, size)
+	return dst
+}
@@ -0,0 +1,94 @@
+package delta
+
+import (
+	"math"
+	"math/bits"
+)
+
+// If the packed values of a block share a common divisor, such as the deltas
+// of millisecond timestamps which are multiples of 1000, the block is scaled:
+// the values are packed divided by it, and the divisor (uint64) follows the
+// first value, before the exceptions of a patched block. Exceptions are not
+// divided. Scaled blocks flag their header by setting scaledFlag in the value
+// count, which never exceeds Int64BlockSize.
+
+const (
+	// scaledFlag is set in the value count of the header of scaled blocks.
+	scaledFlag = 0x8000
+
+	// divisorSize is the size in bytes of the divisor of a scaled block.
+	divisorSize = 8
+)
+
+// divisor divides values exactly by a common divisor by multiplying them with
+// the modular inverse of its odd factor, which is much cheaper than dividing.
+type divisor struct {
+	shift uint   // Trailing zeros of the divisor
+	inv   uint64 // Inverse of the odd factor modulo 2^64
+	limit uint64 // Largest quotient of a multiple of the odd factor
+}
+
+// newDivisor returns a divisor for d. The zero divisor, which is only divided
+// by 0, has a shift of 64.
+func newDivisor(d uint64) divisor {
+	if d == 0 {
+		return divisor{shift: 64}
+	}
+	shift := uint(bits.TrailingZeros64(d))
+	odd := d >> shift
+	// Newton's iteration doubles the number of correct low bits of the
+	// inverse in every step, starting from the 3 bits of odd*odd == 1 mod 8.
+	inv := odd
+	for range 5 {
+		inv *= 2 - odd*inv
+	}
+	return divisor{shift: shift, inv: inv, limit: math.MaxUint64 / odd}
+}
+
+// divides reports whether x is a multiple of the divisor.
+func (d divisor) divides(x uint64) bool {
+	return x&(1<<d.shift-1) == 0 && d.quotient(x) <= d.limit
+}
+
+// quotient returns x divided by the divisor, if it is a multiple of it.
+func (d divisor) quotient(x uint64) uint64 {
+	return (x >> d.shift) * d.inv
+}
+
+// commonDivisor returns the greatest common divisor of the values which are
+// packed with base, which are within span of it. Values which are all equal
+// to base have a common divisor of 0.
+func commonDivisor(values []int64, base int64, span uint64) uint64 {
+	var (
+		gcd uint64
+		d   = newDivisor(0)
+	)
+	for _, v := range values {
+		x := uint64(v - base)
+		if x > span || d.divides(x) {
+			continue
+		}
+		if gcd = binaryGCD(gcd, x); gcd == 1 {
+			return 1
+		}
+		d = newDivisor(gcd)
+	}
+	return gcd
+}
+
+// binaryGCD returns the greatest common divisor of a and b.
+func binaryGCD(a, b uint64) uint64 {
+	if a == 0 || b == 0 {
+		return a | b
+	}
+	shift := bits.TrailingZeros64(a | b)
+	a >>= bits.TrailingZeros64(a)
+	for b != 0 {
+		b >>= bits.TrailingZeros64(b)
+		if a > b {
+			a, b = b, a
+		}
+		b -= a
+	}
+	return a << shift
+}
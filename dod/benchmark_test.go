@@ -358,3 +358,31 @@ func BenchmarkUInt64(b *testing.B) {
 		})
 	})
 }
+
+func BenchmarkRegular(b *testing.B) {
+	src := make([]int64, benchmarkSize)
+	for i := range src {
+		src[i] = 1700000000000 + int64(i)*15000 // Unix milliseconds every 15s
+	}
+	encoded := EncodeInt64(nil, src)
+
+	b.Run("decode", func(b *testing.B) {
+		dst := make([]int64, benchmarkSize)
+		b.ResetTimer()
+		b.ReportAllocs()
+
+		for b.Loop() {
+			DecodeInt64(dst, encoded)
+		}
+	})
+
+	b.Run("search", func(b *testing.B) {
+		b.ResetTimer()
+		b.ReportAllocs()
+
+		for i := 0; b.Loop(); i++ {
+			r, _ := DecodeRegular[int64](encoded)
+			r.Search(src[i%benchmarkSize] + 1)
+		}
+	})
+}
@@ -0,0 +1,61 @@
+package dod
+
+import "github.com/fpetkovski/tscodec-go/delta"
+
+// EncodeInt64Blocks encodes any number of values by splitting them into blocks
+// of BlockSize values, each encoded with EncodeInt64. The layout of the
+// sequence of blocks is the one of delta.EncodeInt64Blocks.
+func EncodeInt64Blocks(dst []byte, src []int64) []byte {
+	var e Encoder
+	return delta.EncodeBlocks(dst, src, e.EncodeInt64)
+}
+
+// DecodeInt64Blocks decodes values encoded with EncodeInt64Blocks into dst,
+// growing it if needed.
+func DecodeInt64Blocks(dst []int64, src []byte) []int64 {
+	return delta.DecodeBlocks(dst, src, DecodeInt64)
+}
+
+// DecodeInt64BlocksChecked is like DecodeInt64Blocks but validates src before
+// decoding it, see DecodeInt64Checked.
+func DecodeInt64BlocksChecked(dst []int64, src []byte) ([]int64, error) {
+	return delta.DecodeBlocksChecked(dst, src, DecodeInt64Checked)
+}
+
+// EncodeInt32Blocks encodes any number of values by splitting them into blocks
+// of BlockSize values, each encoded with EncodeInt32.
+func EncodeInt32Blocks(dst []byte, src []int32) []byte {
+	var e Encoder
+	return delta.EncodeBlocks(dst, src, e.EncodeInt32)
+}
+
+// DecodeInt32Blocks decodes values encoded with EncodeInt32Blocks into dst,
+// growing it if needed.
+func DecodeInt32Blocks(dst []int32, src []byte) []int32 {
+	return delta.DecodeBlocks(dst, src, DecodeInt32)
+}
+
+// DecodeInt32BlocksChecked is like DecodeInt32Blocks but validates src before
+// decoding it, see DecodeInt32Checked.
+func DecodeInt32BlocksChecked(dst []int32, src []byte) ([]int32, error) {
+	return delta.DecodeBlocksChecked(dst, src, DecodeInt32Checked)
+}
+
+// EncodeUInt64Blocks encodes any number of values by splitting them into
+// blocks of BlockSize values, each encoded with EncodeUInt64.
+func EncodeUInt64Blocks(dst []byte, src []uint64) []byte {
+	var e Encoder
+	return delta.EncodeBlocks(dst, src, e.EncodeUInt64)
+}
+
+// DecodeUInt64Blocks decodes values encoded with EncodeUInt64Blocks into dst,
+// growing it if needed.
+func DecodeUInt64Blocks(dst []uint64, src []byte) []uint64 {
+	return delta.DecodeBlocks(dst, src, DecodeUInt64)
+}
+
+// DecodeUInt64BlocksChecked is like DecodeUInt64Blocks but validates src
+// before decoding it, see DecodeUInt64Checked.
+func DecodeUInt64BlocksChecked(dst []uint64, src []byte) ([]uint64, error) {
+	return delta.DecodeBlocksChecked(dst, src, DecodeUInt64Checked)
+}
@@ -0,0 +1,161 @@
+// Package dod encodes integers as the differences between consecutive
+// deltas, which are zero or close to it for regular series such as scrape
+// timestamps. Blocks have the layout of the delta package.
+//
+// # Block size
+//
+// A block holds at most BlockSize values, and the encoders of single blocks
+// panic on longer inputs. This is a breaking change: they used to accept them
+// and silently truncate the value count in the header. Longer series are
+// split into a sequence of blocks by EncodeInt64Blocks, EncodeInt32Blocks and
+// EncodeUInt64Blocks.
+//
+// # Value range
+//
+// Like in the delta package, values of every integer type are encoded
+// losslessly over their whole range. The deltas and delta-of-deltas of 64-bit
+// values are computed modulo 2^64, and their distance from the smallest
+// delta-of-delta is packed with up to 64 bits.
+package dod
+
+import (
+	"slices"
+	"unsafe"
+
+	"github.com/parquet-go/bitpack/unsafecast"
+
+	"github.com/fpetkovski/tscodec-go/delta"
+)
+
+// Integer is the set of integer types which can be encoded, see
+// delta.Integer.
+type Integer = delta.Integer
+
+// Regular is the series of a regular block, whose values all differ by the
+// same interval. Encoders write regular blocks for such series, which store
+// only the first value and the interval, see delta.Regular.
+type Regular[T Integer] = delta.Regular[T]
+
+// DecodeRegular returns the series of a regular block of values of type T,
+// and false if src is not a regular block. Its values are accessed without
+// decoding the block, see delta.DecodeRegular.
+func DecodeRegular[T Integer](src []byte) (Regular[T], bool) {
+	return delta.DecodeRegular[T](src)
+}
+
+// isRegular reports whether all delta-of-deltas are zero.
+func isRegular(dods []int64) bool {
+	for _, d := range dods {
+		if d != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Encode encodes src into a single block, reusing the capacity of dst. The
+// first value is stored with the size of T and the delta-of-deltas are
+// bit-packed as int64, so blocks of int64, uint64 and int32 values are the
+// ones of EncodeInt64, EncodeUInt64 and EncodeInt32. Differences of 64-bit
+// values wrap around, see the package documentation. Encode panics if src
+// holds more than BlockSize values.
+//
+// Sequences of blocks can be encoded by passing Encode to
+// delta.EncodeBlocks.
+func Encode[T Integer](dst []byte, src []T) []byte {
+	var e Encoder
+	return EncodeWith(&e, dst, src)
+}
+
+// EncodeWith is like Encode, but reuses the scratch buffer of e.
+func EncodeWith[T Integer](e *Encoder, dst []byte, src []T) []byte {
+	checkBlockSize(len(src))
+	switch len(src) {
+	case 0:
+		return dst
+	case 1:
+		dst = slices.Grow(dst, delta.HeaderSize)[:delta.HeaderSize]
+		delta.EncodeHeader(dst, 1, int64(src[0]), 0)
+		return dst
+	}
+
+	// Differences of values narrower than 64 bits cannot overflow int64,
+	// while differences of 64-bit values wrap around.
+	d0 := int64(0)
+	encoded := e.scratch(len(src))
+	encoded[0] = int64(src[0])
+	for i := 1; i < len(src); i++ {
+		d1 := int64(src[i]) - int64(src[i-1])
+		encoded[i] = d1 - d0
+		d0 = d1
+	}
+	if isRegular(encoded[2:]) {
+		return delta.PackRegular(dst, len(src), uint64(src[0]), encoded[1], sizeOf[T]())
+	}
+	return delta.PackBlock(dst, encoded, sizeOf[T]())
+}
+
+// Decode decodes a block encoded with Encode into dst and returns the number
+// of decoded values. Blocks of 64-bit values are decoded with DecodeInt64.
+func Decode[T Integer](dst []T, src []byte) uint16 {
+	if sizeOf[T]() == delta.Int64SizeBytes {
+		return DecodeInt64(unsafecast.Slice[int64](dst), src)
+	}
+	if len(src) == 0 {
+		return 0
+	}
+
+	header := delta.DecodeHeader(src)
+	if header.NumValues == 1 {
+		dst[0] = T(header.MinVal)
+		return 1
+	}
+	size := sizeOf[T]()
+	dst[0] = T(delta.FirstValue(src[delta.HeaderSize:], size))
+	if header.Regular {
+		Regular[T]{Start: dst[0], Interval: T(header.MinVal)}.Fill(dst[:header.NumValues])
+		return header.NumValues
+	}
+
+	var (
+		minVal = header.MinVal
+		d0     = int64(0)
+		prev   = int64(dst[0])
+		buf    [decodeChunkSize]int64
+	)
+	// Delta-of-deltas are unpacked a chunk at a time into a buffer on the
+	// stack.
+	for i := 1; i < int(header.NumValues); i += decodeChunkSize {
+		dods := buf[:min(int(header.NumValues)-i, decodeChunkSize)]
+		delta.UnpackValues(dods, src, header, size, i-1)
+		values := dst[i : i+len(dods)]
+		for j, dod := range dods {
+			d0 += dod + minVal
+			prev += d0
+			values[j] = T(prev)
+		}
+	}
+	return header.NumValues
+}
+
+// DecodeChecked is like Decode but validates src before decoding it, see
+// DecodeInt64Checked.
+func DecodeChecked[T Integer](dst []T, src []byte) (uint16, error) {
+	if len(src) == 0 {
+		return 0, nil
+	}
+	if _, err := delta.CheckBlock(src, len(dst), sizeOf[T]()); err != nil {
+		return 0, err
+	}
+	return Decode(dst, src), nil
+}
+
+// decodeChunkSize is the number of delta-of-deltas unpacked at a time by
+// Decode. It is a multiple of 8, so that every chunk starts at a byte
+// boundary.
+const decodeChunkSize = 256
+
+func sizeOf[T Integer]() int {
+	var v T
+	return int(unsafe.Sizeof(v))
+}
@@ -2,68 +2,71 @@ package dod
 
 import (
 	"encoding/binary"
-	"math"
+	"fmt"
 
-	"github.com/parquet-go/bitpack"
-
-	"github.com/fpetkovski/tscodec-go/alp"
 	"github.com/fpetkovski/tscodec-go/delta"
+	"github.com/fpetkovski/tscodec-go/internal/prefixsum"
 )
 
 const (
 	// BlockSize is the maximum amount of values that can be encoded at once.
+	// Longer inputs can be encoded with EncodeInt64Blocks.
 	BlockSize = delta.Int64BlockSize
+
+	// ChecksumSize is the size in bytes of the checksum trailer.
+	ChecksumSize = delta.ChecksumSize
+)
+
+var (
+	// ErrCorrupt is returned when encoded data is truncated or malformed.
+	ErrCorrupt = delta.ErrCorrupt
+	// ErrShortBuffer is returned when the destination cannot hold the
+	// decoded values.
+	ErrShortBuffer = delta.ErrShortBuffer
+	// ErrChecksum is returned when the checksum of a block does not match.
+	ErrChecksum = delta.ErrChecksum
 )
 
+// AppendChecksum flags a block produced by one of the encoders as checksummed
+// and appends a CRC32C trailer over all of its bytes, see delta.AppendChecksum.
+func AppendChecksum(block []byte) []byte {
+	return delta.AppendChecksum(block)
+}
+
 type Int64Block [BlockSize]int64
 
+// EncodeInt64 encodes src into a single block, reusing the capacity of dst. It
+// panics if src holds more than BlockSize values.
 func EncodeInt64(dst []byte, src []int64) []byte {
-	switch len(src) {
-	case 0:
-		return dst
-	case 1:
-		if cap(dst) < delta.HeaderSize {
-			dst = make([]byte, delta.HeaderSize)
-		}
-		dst = dst[:delta.HeaderSize]
-		delta.EncodeHeader(dst, 1, src[0], 0)
-		return dst
-	}
+	var e Encoder
+	return e.EncodeInt64(dst, src)
+}
 
-	d0 := int64(0)
-	minVal := int64(math.MaxInt64)
-	encoded := make([]int64, len(src))
-	encoded[0] = src[0]
-	for i := 1; i < len(src); i++ {
-		d1 := src[i] - src[i-1]
-		dod1 := d1 - d0
-		d0 = d1
-		encoded[i] = dod1
-		minVal = min(minVal, dod1)
-	}
-	for i := 1; i < len(encoded); i++ {
-		encoded[i] = encoded[i] - minVal
-	}
+// EncodeInt64 is like the EncodeInt64 function, but reuses the scratch buffer of e.
+func (e *Encoder) EncodeInt64(dst []byte, src []int64) []byte {
+	return EncodeWith(e, dst, src)
+}
 
-	bitWidth := 0
-	for _, v := range encoded[1:] {
-		bw := alp.CalculateBitWidth(uint64(v))
-		bitWidth = max(bitWidth, bw)
+// checkBlockSize panics if n values do not fit into a single block, since the
+// count in the header would silently overflow.
+func checkBlockSize(n int) {
+	if n > BlockSize {
+		panic(fmt.Sprintf("dod: %d values exceed the block size of %d", n, BlockSize))
 	}
+}
 
-	packedSize := bitpack.ByteCount(uint((len(encoded) - 1) * bitWidth))
-	totalSize := packedSize + delta.Int64SizeBytes + delta.HeaderSize + bitpack.PaddingInt64
-	if cap(dst) < totalSize {
-		dst = make([]byte, totalSize)
+// DecodeInt64Checked is like DecodeInt64 but validates src before decoding it.
+// It returns an error wrapping ErrCorrupt for truncated or malformed input,
+// ErrShortBuffer if dst cannot hold the encoded values and ErrChecksum if the
+// checksum trailer of the block does not match.
+func DecodeInt64Checked(dst []int64, src []byte) (uint16, error) {
+	if len(src) == 0 {
+		return 0, nil
 	}
-	dst = dst[:totalSize]
-
-	delta.EncodeHeader(dst, uint16(len(src)), minVal, uint8(bitWidth))
-	// Encode the first value as is and bitpack the rest.
-	binary.LittleEndian.PutUint64(dst[delta.HeaderSize:delta.HeaderSize+delta.Int64SizeBytes], uint64(encoded[0]))
-	bitpack.Pack(dst[delta.HeaderSize+delta.Int64SizeBytes:], encoded[1:], uint(bitWidth))
-
-	return dst
+	if _, err := delta.CheckBlock(src, len(dst), delta.Int64SizeBytes); err != nil {
+		return 0, err
+	}
+	return DecodeInt64(dst, src), nil
 }
 
 func DecodeInt64(dst []int64, src []byte) uint16 {
@@ -78,50 +81,16 @@ func DecodeInt64(dst []int64, src []byte) uint16 {
 		return 1
 	}
 	dst[0] = int64(binary.LittleEndian.Uint64(src[delta.HeaderSize : delta.HeaderSize+delta.Int64SizeBytes]))
-	bitpack.Unpack(dst[1:header.NumValues], src[delta.HeaderSize+delta.Int64SizeBytes:], uint(header.BitWidth))
-
-	numVals := int(header.NumValues)
-	// Bounds check hint
-	_ = dst[numVals-1]
-
-	// Add minVal to all unpacked values first using SIMD
-	minVal := header.MinVal
-	addConstInt64(dst[1:numVals], minVal)
-
-	// Now reconstruct DoD without minVal in critical path
-	d0 := int64(0)
-	prev := dst[0]
-	i := 1
-
-	// Loop unrolling - process 4 elements at a time
-	for ; i+3 < numVals; i += 4 {
-		d1 := dst[i] + d0
-		prev = d1 + prev
-		dst[i] = prev
-		d0 = d1
-
-		d1 = dst[i+1] + d0
-		prev = d1 + prev
-		dst[i+1] = prev
-		d0 = d1
-
-		d1 = dst[i+2] + d0
-		prev = d1 + prev
-		dst[i+2] = prev
-		d0 = d1
-
-		d1 = dst[i+3] + d0
-		prev = d1 + prev
-		dst[i+3] = prev
-		d0 = d1
+	if header.Regular {
+		delta.Regular[int64]{Start: dst[0], Interval: header.MinVal}.Fill(dst[:header.NumValues])
+		return header.NumValues
 	}
+	delta.UnpackValues(dst[1:header.NumValues], src, header, delta.Int64SizeBytes, 0)
 
-	// Handle remaining elements
-	for ; i < numVals; i++ {
-		d1 := dst[i] + d0
-		prev = d1 + prev
-		dst[i] = prev
-		d0 = d1
-	}
+	numVals := int(header.NumValues)
+	// Add minVal to all unpacked values, then reconstruct the deltas and
+	// the values with two prefix sums.
+	prefixsum.Int64(dst[1:numVals], 0, header.MinVal)
+	prefixsum.Int64(dst[1:numVals], dst[0], 0)
 	return header.NumValues
 }
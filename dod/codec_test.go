@@ -1,9 +1,16 @@
 package dod
 
 import (
+	"encoding/binary"
+	"errors"
+	"math"
 	"math/rand"
 	"slices"
 	"testing"
+
+	"github.com/parquet-go/bitpack/unsafecast"
+
+	"github.com/fpetkovski/tscodec-go/delta"
 )
 
 func TestEncode(t *testing.T) {
@@ -118,3 +125,561 @@ func FuzzInt32(f *testing.F) {
 		}
 	})
 }
+
+func TestDecodeChecked(t *testing.T) {
+	encoded := EncodeInt64(nil, []int64{1000, 2000, 3000, 4001, 5003})
+
+	var block Int64Block
+	n, err := DecodeInt64Checked(block[:], encoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []int64{1000, 2000, 3000, 4001, 5003}; !slices.Equal(block[:n], want) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", block[:n], want)
+	}
+
+	if _, err := DecodeInt64Checked(block[:], encoded[:len(encoded)-10]); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+	if _, err := DecodeInt64Checked(block[:4], encoded); !errors.Is(err, ErrShortBuffer) {
+		t.Fatalf("expected ErrShortBuffer, got %v", err)
+	}
+	var block32 Int32Block
+	if _, err := DecodeInt32Checked(block32[:], encoded[:5]); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+	var blockUint64 Uint64Block
+	if _, err := DecodeUInt64Checked(blockUint64[:], encoded[:len(encoded)-10]); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+}
+
+func FuzzDecodeChecked(f *testing.F) {
+	f.Add(EncodeInt64(nil, []int64{1000, 2000, 3000, 4001, 5003}))
+	f.Add(EncodeInt32(nil, []int32{-5, 7, 1 << 30}))
+	f.Add(EncodeUInt64(nil, []uint64{1 << 63, 3, 1}))
+	f.Add(EncodeInt64(nil, []int64{1000, 2000, 3000, 4000}))
+	f.Add(EncodeInt64(nil, []int64{1000, 2000, 3000, 5000, 6000, 7000, 8000, 9000, 10000}))
+	f.Add(EncodeInt64(nil, []int64{0, 15000, 31000, 45000, 59000, 75000, 90000, 106000, 120000, 134000, 150000, 165000, 181000, 195000}))
+
+	f.Fuzz(func(t *testing.T, src []byte) {
+		var (
+			block64     Int64Block
+			block32     Int32Block
+			blockUint64 Uint64Block
+		)
+		_, _ = DecodeInt64Checked(block64[:], src)
+		_, _ = DecodeInt32Checked(block32[:], src)
+		_, _ = DecodeUInt64Checked(blockUint64[:], src)
+	})
+}
+
+func TestChecksum(t *testing.T) {
+	src := []uint64{1 << 63, 1<<63 + 10, 1<<63 + 20, 1<<63 + 31}
+	encoded := AppendChecksum(EncodeUInt64(nil, src))
+
+	var decoded Uint64Block
+	n, err := DecodeUInt64Checked(decoded[:], encoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(src, decoded[:n]) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], src)
+	}
+
+	encoded[len(encoded)-ChecksumSize-1] ^= 0x01
+	if _, err := DecodeUInt64Checked(decoded[:], encoded); !errors.Is(err, ErrChecksum) {
+		t.Fatalf("expected ErrChecksum, got %v", err)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	for _, size := range []int{0, 1, BlockSize, 3*BlockSize + 7} {
+		src := make([]int64, size)
+		val := int64(1700000000000)
+		for i := range src {
+			val += 15000 + rand.Int63n(100)
+			src[i] = val
+		}
+		decoded, err := DecodeInt64BlocksChecked(nil, EncodeInt64Blocks(nil, src))
+		if err != nil {
+			t.Fatalf("size %d: unexpected error: %v", size, err)
+		}
+		if !slices.Equal(src, decoded) {
+			t.Fatalf("size %d: Slices are not equal", size)
+		}
+
+		vals32 := make([]int32, size)
+		vals64 := make([]uint64, size)
+		for i := range src {
+			vals32[i] = int32(src[i] % 1000000)
+			vals64[i] = uint64(src[i]) << 20
+		}
+		if decoded := DecodeInt32Blocks(nil, EncodeInt32Blocks(nil, vals32)); !slices.Equal(vals32, decoded) {
+			t.Fatalf("size %d: Slices are not equal", size)
+		}
+		if decoded := DecodeUInt64Blocks(nil, EncodeUInt64Blocks(nil, vals64)); !slices.Equal(vals64, decoded) {
+			t.Fatalf("size %d: Slices are not equal", size)
+		}
+	}
+}
+
+func TestBlockSizeLimit(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic")
+		}
+	}()
+	EncodeUInt64(nil, make([]uint64, BlockSize+1))
+}
+
+func TestEncoderAllocs(t *testing.T) {
+	gen := rand.New(rand.NewSource(25))
+	// Timestamps scraped every 15s, offset by up to jitter multiples of step.
+	timestamps := func(jitter, step int64) []int64 {
+		src := make([]int64, 120)
+		for i := range src {
+			src[i] = 1_700_000_000_000 + int64(i)*15_000 + gen.Int63n(jitter+1)*step
+		}
+		return src
+	}
+	missed := timestamps(100, 1)
+	for i := 60; i < len(missed); i++ {
+		missed[i] += 15_000
+	}
+	tests := []struct {
+		name string
+		src  []int64
+	}{
+		{
+			name: "jittered timestamps",
+			src:  timestamps(100, 1),
+		},
+		{
+			name: "regular timestamps",
+			src:  timestamps(0, 1),
+		},
+		{
+			name: "scaled timestamps",
+			src:  timestamps(3, 1000),
+		},
+		{
+			name: "patched timestamps",
+			src:  missed,
+		},
+		{
+			name: "empty source",
+			src:  nil,
+		},
+	}
+
+	var (
+		e   Encoder
+		dst []byte
+	)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Values relative to the first timestamp fit in int32.
+			vals32 := make([]int32, len(tt.src))
+			vals64 := make([]uint64, len(tt.src))
+			for i, v := range tt.src {
+				vals32[i] = int32(v - 1_700_000_000_000)
+				vals64[i] = uint64(v)
+			}
+			// dst is reused with the blocks of previous cases beyond its length.
+			dst = e.EncodeInt64(dst[:0], tt.src)
+			allocs := testing.AllocsPerRun(10, func() {
+				dst = e.EncodeInt32(dst[:0], vals32)
+				dst = e.EncodeUInt64(dst[:0], vals64)
+				dst = e.EncodeInt64(dst[:0], tt.src)
+			})
+			if allocs != 0 {
+				t.Fatalf("expected no allocations, got %v", allocs)
+			}
+
+			if want := EncodeInt64(nil, tt.src); !slices.Equal(dst, want) {
+				t.Fatalf("encoder output differs from EncodeInt64")
+			}
+			var decoded Int64Block
+			n := DecodeInt64(decoded[:], dst)
+			if !slices.Equal(tt.src, decoded[:n]) {
+				t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], tt.src)
+			}
+			dst = e.EncodeInt32(dst[:0], vals32)
+			var decoded32 Int32Block
+			n = DecodeInt32(decoded32[:], dst)
+			if !slices.Equal(vals32, decoded32[:n]) {
+				t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded32[:n], vals32)
+			}
+			dst = e.EncodeUInt64(dst[:0], vals64)
+			decoded64 := make([]uint64, BlockSize)
+			n = DecodeUInt64(decoded64, dst)
+			if !slices.Equal(vals64, decoded64[:n]) {
+				t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded64[:n], vals64)
+			}
+		})
+	}
+}
+
+func TestGeneric(t *testing.T) {
+	gen := rand.New(rand.NewSource(23))
+	t.Run("int8", func(t *testing.T) { testGeneric[int8](t, gen) })
+	t.Run("uint8", func(t *testing.T) { testGeneric[uint8](t, gen) })
+	t.Run("int16", func(t *testing.T) { testGeneric[int16](t, gen) })
+	t.Run("uint16", func(t *testing.T) { testGeneric[uint16](t, gen) })
+	t.Run("int32", func(t *testing.T) { testGeneric[int32](t, gen) })
+	t.Run("uint32", func(t *testing.T) { testGeneric[uint32](t, gen) })
+	t.Run("int64", func(t *testing.T) { testGeneric[int64](t, gen) })
+	t.Run("uint64", func(t *testing.T) { testGeneric[uint64](t, gen) })
+	t.Run("uint", func(t *testing.T) { testGeneric[uint](t, gen) })
+
+	// Blocks of int64 and int32 values are the ones of the typed encoders.
+	src := make([]int64, 1000)
+	for i := range src {
+		src[i] = gen.Int63n(1 << 40)
+	}
+	if got, want := Encode(nil, src), EncodeInt64(nil, src); !slices.Equal(got, want) {
+		t.Fatalf("Encode differs from EncodeInt64")
+	}
+	src32 := make([]int32, 1000)
+	for i := range src32 {
+		src32[i] = int32(gen.Uint32())
+	}
+	if got, want := Encode(nil, src32), EncodeInt32(nil, src32); !slices.Equal(got, want) {
+		t.Fatalf("Encode differs from EncodeInt32")
+	}
+	src64 := unsafecast.Slice[uint64](src)
+	if got, want := Encode(nil, src64), EncodeUInt64(nil, src64); !slices.Equal(got, want) {
+		t.Fatalf("Encode differs from EncodeUInt64")
+	}
+}
+
+func testGeneric[T Integer](t *testing.T, gen *rand.Rand) {
+	var (
+		zero   T
+		minVal = ^zero
+		maxVal = ^zero
+	)
+	if minVal < 0 {
+		// Signed types have the sign bit set in their minimum.
+		maxVal = T(uint64(maxVal) >> 1)
+		minVal = ^maxVal
+	} else {
+		minVal = 0
+	}
+	datasets := [][]T{
+		nil,
+		{maxVal},
+		{minVal, maxVal, minVal, 0, maxVal},
+		{1, 2, 3, 5, 8, 13, 21, 34, 55, 89},
+	}
+	for _, n := range []int{7, 1000, BlockSize} {
+		values := make([]T, n)
+		for i := range values {
+			values[i] = T(gen.Uint64())
+		}
+		datasets = append(datasets, values)
+	}
+
+	var e Encoder
+	for _, src := range datasets {
+		encoded := EncodeWith(&e, nil, src)
+
+		decoded := make([]T, len(src))
+		if n := Decode(decoded, encoded); !slices.Equal(decoded[:n], src) {
+			t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], src)
+		}
+		n, err := DecodeChecked(decoded, AppendChecksum(encoded))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(decoded[:n], src) {
+			t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], src)
+		}
+	}
+
+	var (
+		src     = datasets[len(datasets)-1]
+		dst     = EncodeWith(&e, nil, src)
+		decoded = make([]T, len(src))
+	)
+	allocs := testing.AllocsPerRun(10, func() {
+		dst = EncodeWith(&e, dst[:0], src)
+		Decode(decoded, dst)
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+
+	src = slices.Repeat(src, 3)
+	encoded := delta.EncodeBlocks(nil, src, Encode[T])
+	if decoded := delta.DecodeBlocks(nil, encoded, Decode[T]); !slices.Equal(decoded, src) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded, src)
+	}
+	decoded, err := delta.DecodeBlocksChecked(nil, encoded, DecodeChecked[T])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded, src) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded, src)
+	}
+}
+
+// fullRangeSeeds are sequences of 64-bit values which span the whole range,
+// see the package documentation.
+var fullRangeSeeds = [][]uint64{
+	{math.MaxInt64 + 1, math.MaxInt64},
+	{math.MaxInt64, math.MaxInt64 + 1, math.MaxInt64, math.MaxInt64 + 1},
+	{0, math.MaxUint64, 0, math.MaxUint64, 1},
+	{math.MaxInt64 - 1, math.MaxInt64, math.MaxInt64 + 1, math.MaxInt64 + 2},
+	{0, math.MaxInt64 + 1, math.MaxUint64, 1, math.MaxInt64, math.MaxInt64 + 2, math.MaxUint64 - 1},
+	{0x9e3779b97f4a7c15, 0x3c6ef372fe94f82a, 0xdaa66d2c7ddf743f, 0x78dde6e5fd29f054},
+}
+
+func TestFullRange(t *testing.T) {
+	for _, values := range fullRangeSeeds {
+		testFullRange(t, values)
+	}
+
+	// Delta-of-deltas spread over the whole range, from math.MinInt64 to
+	// math.MaxInt64, are packed with 64 bits.
+	values := []uint64{0, math.MaxInt64 + 1, math.MaxInt64}
+	gen := rand.New(rand.NewSource(22))
+	for range 61 {
+		values = append(values, gen.Uint64())
+	}
+	if header := delta.DecodeHeader(Encode(nil, values)); header.BitWidth != 64 || header.Patched {
+		t.Fatalf("expected a bit width of 64, got %d", header.BitWidth)
+	}
+	testFullRange(t, values)
+}
+
+// FuzzFullRange round-trips arbitrary 64-bit values, read from data as
+// little-endian uint64s. The corpus in testdata holds boundary values.
+func FuzzFullRange(f *testing.F) {
+	for _, values := range fullRangeSeeds {
+		var data []byte
+		for _, v := range values {
+			data = binary.LittleEndian.AppendUint64(data, v)
+		}
+		f.Add(data)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		values := make([]uint64, min(len(data)/8, BlockSize))
+		for i := range values {
+			values[i] = binary.LittleEndian.Uint64(data[i*8:])
+		}
+		testFullRange(t, values)
+	})
+}
+
+// testFullRange checks that values round-trip as uint64 and int64, and that
+// both encode to the same block.
+func testFullRange(t *testing.T, values []uint64) {
+	encoded := EncodeUInt64(nil, values)
+	ints := unsafecast.Slice[int64](values)
+	if want := EncodeInt64(nil, ints); !slices.Equal(encoded, want) {
+		t.Fatalf("uint64 and int64 blocks differ")
+	}
+
+	decoded := make([]uint64, len(values))
+	n, err := DecodeUInt64Checked(decoded, encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded[:n], values) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], values)
+	}
+	decodedInts := make([]int64, len(ints))
+	if n := DecodeInt64(decodedInts, encoded); !slices.Equal(decodedInts[:n], ints) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decodedInts[:n], ints)
+	}
+}
+
+func TestPatched(t *testing.T) {
+	// Timestamps scraped every 15s with jitter and a few missed scrapes.
+	timestamps := make([]int64, BlockSize)
+	gen := rand.New(rand.NewSource(23))
+	ts := int64(1_700_000_000_000)
+	for i := range timestamps {
+		ts += 15_000 + gen.Int63n(8)
+		if i%300 == 150 {
+			ts += 15_000 * int64(1+i%4)
+		}
+		timestamps[i] = ts
+	}
+	encoded := EncodeInt64(nil, timestamps)
+	header := delta.DecodeHeader(encoded)
+	if !header.Patched || header.BitWidth > 4 {
+		t.Fatalf("expected a patched block with a bit width of at most 4, got %d", header.BitWidth)
+	}
+	decoded := make([]int64, len(timestamps))
+	n, err := DecodeInt64Checked(decoded, encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded[:n], timestamps) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], timestamps)
+	}
+
+	// Narrow types are decoded in chunks, with exceptions in several of them.
+	narrow := make([]int32, 1000)
+	for i := range narrow {
+		narrow[i] = int32(i * 60)
+		if i%250 == 249 {
+			narrow[i] += 1 << 20
+		}
+	}
+	encoded32 := EncodeInt32(nil, narrow)
+	if !delta.DecodeHeader(encoded32).Patched {
+		t.Fatalf("expected a patched block")
+	}
+	decoded32 := make([]int32, len(narrow))
+	n, err = DecodeInt32Checked(decoded32, encoded32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded32[:n], narrow) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded32[:n], narrow)
+	}
+}
+
+func TestScaled(t *testing.T) {
+	// Nanosecond timestamps of whole milliseconds, scraped every 15s with
+	// jitter and a missed scrape.
+	gen := rand.New(rand.NewSource(24))
+	timestamps := make([]int64, BlockSize)
+	ts := int64(1_700_000_000_000_000_000)
+	for i := range timestamps {
+		ts += 15_000_000_000 + gen.Int63n(8)*1_000_000
+		if i == 2000 {
+			ts += 15_000_000_000
+		}
+		timestamps[i] = ts
+	}
+
+	encoded := EncodeInt64(nil, timestamps)
+	header := delta.DecodeHeader(encoded)
+	if !header.Scaled || !header.Patched || header.BitWidth != 4 {
+		t.Fatalf("expected a scaled and patched block with a bit width of 4, got %+v", header)
+	}
+	decoded := make([]int64, len(timestamps))
+	n, err := DecodeInt64Checked(decoded, encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded[:n], timestamps) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], timestamps)
+	}
+
+	// Narrow types are decoded in chunks.
+	narrow := make([]uint32, 1000)
+	for i := range narrow {
+		narrow[i] = uint32(i*1000 + gen.Intn(8)*8)
+	}
+	encoded = Encode(nil, narrow)
+	if !delta.DecodeHeader(encoded).Scaled {
+		t.Fatalf("expected a scaled block")
+	}
+	decoded32 := make([]uint32, len(narrow))
+	n, err = DecodeChecked(decoded32, encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded32[:n], narrow) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded32[:n], narrow)
+	}
+}
+
+func TestRegular(t *testing.T) {
+	// Timestamps scraped every 15s without jitter.
+	const (
+		start    = int64(1_700_000_000_000)
+		interval = int64(15_000)
+	)
+	timestamps := make([]int64, 120)
+	for i := range timestamps {
+		timestamps[i] = start + int64(i)*interval
+	}
+
+	encoded := EncodeInt64(nil, timestamps)
+	if len(encoded) != delta.HeaderSize+delta.Int64SizeBytes {
+		t.Fatalf("expected a block of %d bytes, got %d", delta.HeaderSize+delta.Int64SizeBytes, len(encoded))
+	}
+	decoded := make([]int64, len(timestamps))
+	n, err := DecodeInt64Checked(decoded, encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded[:n], timestamps) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded[:n], timestamps)
+	}
+
+	r, ok := DecodeRegular[int64](encoded)
+	if want := (Regular[int64]{Start: start, Interval: interval, Len: len(timestamps)}); !ok || r != want {
+		t.Fatalf("expected %+v, got %+v", want, r)
+	}
+	for i, ts := range timestamps {
+		if got := r.At(i); got != ts {
+			t.Fatalf("expected %d at %d, got %d", ts, i, got)
+		}
+		if i, found := r.Search(ts - 1); i != slices.Index(timestamps, ts) || found {
+			t.Fatalf("expected %d to be inserted at %d, got %d, %v", ts-1, slices.Index(timestamps, ts), i, found)
+		}
+	}
+	for _, ts := range []int64{math.MinInt64, start - 1, start, start + 1, start + 7*interval, start + 7*interval + 1, timestamps[len(timestamps)-1], math.MaxInt64} {
+		wantIndex, wantFound := slices.BinarySearch(timestamps, ts)
+		if i, found := r.Search(ts); i != wantIndex || found != wantFound {
+			t.Fatalf("expected %d, %v for %d, got %d, %v", wantIndex, wantFound, ts, i, found)
+		}
+	}
+
+	// Series of other types, which wrap around.
+	values := []uint64{math.MaxUint64 - 2, math.MaxUint64, 1, 3, 5}
+	encoded = EncodeUInt64(nil, values)
+	if r, ok := DecodeRegular[uint64](encoded); !ok || r.At(4) != 5 {
+		t.Fatalf("expected a regular block ending with 5, got %+v", r)
+	}
+	decodedUint64 := make([]uint64, len(values))
+	if n := DecodeUInt64(decodedUint64, encoded); !slices.Equal(decodedUint64[:n], values) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decodedUint64[:n], values)
+	}
+	narrow := []int8{100, 60, 20, -20, -60, -100}
+	encoded = Encode(nil, narrow)
+	if _, ok := DecodeRegular[int8](encoded); !ok {
+		t.Fatalf("expected a regular block")
+	}
+	decoded8 := make([]int8, len(narrow))
+	if n, err := DecodeChecked(decoded8, encoded); err != nil || !slices.Equal(decoded8[:n], narrow) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v], err: %v", decoded8[:n], narrow, err)
+	}
+
+	// Blocks which are not regular.
+	timestamps[5]++
+	for _, src := range [][]byte{EncodeInt64(nil, timestamps), EncodeInt64(nil, timestamps[:1]), nil} {
+		if _, ok := DecodeRegular[int64](src); ok {
+			t.Fatalf("expected a block which is not regular")
+		}
+	}
+}
+
+func TestRegularChecked(t *testing.T) {
+	encoded := EncodeInt64(nil, []int64{10, 20, 30, 40})
+	var block Int64Block
+	if _, err := DecodeInt64Checked(block[:], encoded[:len(encoded)-1]); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+	// Regular blocks do not pack values.
+	packed := slices.Clone(encoded)
+	packed[delta.Int64SizeBytes+2] = 1
+	if _, err := DecodeInt64Checked(block[:], packed); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected ErrCorrupt, got %v", err)
+	}
+	if _, err := DecodeInt64Checked(block[:3], encoded); !errors.Is(err, ErrShortBuffer) {
+		t.Fatalf("expected ErrShortBuffer, got %v", err)
+	}
+	withChecksum := AppendChecksum(slices.Clone(encoded))
+	if n, err := DecodeInt64Checked(block[:], withChecksum); err != nil || !slices.Equal(block[:n], []int64{10, 20, 30, 40}) {
+		t.Fatalf("unexpected result %v: %v", block[:n], err)
+	}
+}
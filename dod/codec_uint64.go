@@ -2,63 +2,39 @@ package dod
 
 import (
 	"encoding/binary"
-	"math"
 
-	"github.com/parquet-go/bitpack"
 	"github.com/parquet-go/bitpack/unsafecast"
 
-	"github.com/fpetkovski/tscodec-go/alp"
 	"github.com/fpetkovski/tscodec-go/delta"
+	"github.com/fpetkovski/tscodec-go/internal/prefixsum"
 )
 
 type Uint64Block [BlockSize]uint64
 
+// EncodeUInt64 encodes src into a single block, reusing the capacity of dst. It
+// panics if src holds more than BlockSize values. Values are encoded like
+// their bits as int64, so values above math.MaxInt64 are encoded losslessly,
+// see the package documentation.
 func EncodeUInt64(dst []byte, src []uint64) []byte {
-	switch len(src) {
-	case 0:
-		return dst
-	case 1:
-		if cap(dst) < delta.HeaderSize {
-			dst = make([]byte, delta.HeaderSize)
-		}
-		dst = dst[:delta.HeaderSize]
-		delta.EncodeHeader(dst, 1, int64(src[0]), 0)
-		return dst
-	}
+	var e Encoder
+	return e.EncodeUInt64(dst, src)
+}
 
-	d0 := int64(0)
-	minVal := int64(math.MaxInt64)
-	encoded := make([]int64, len(src))
-	encoded[0] = int64(src[0])
-	for i := 1; i < len(src); i++ {
-		d1 := int64(src[i]) - int64(src[i-1])
-		dod1 := d1 - d0
-		d0 = d1
-		encoded[i] = dod1
-		minVal = min(minVal, dod1)
-	}
-	for i := 1; i < len(encoded); i++ {
-		encoded[i] = encoded[i] - minVal
-	}
+// EncodeUInt64 is like the EncodeUInt64 function, but reuses the scratch buffer of e.
+func (e *Encoder) EncodeUInt64(dst []byte, src []uint64) []byte {
+	return EncodeWith(e, dst, src)
+}
 
-	bitWidth := 0
-	for _, v := range encoded[1:] {
-		bw := alp.CalculateBitWidth(uint64(v))
-		bitWidth = max(bitWidth, bw)
+// DecodeUInt64Checked is like DecodeUInt64 but validates src before decoding
+// it, see DecodeInt64Checked.
+func DecodeUInt64Checked(dst []uint64, src []byte) (uint16, error) {
+	if len(src) == 0 {
+		return 0, nil
 	}
-
-	packedSize := bitpack.ByteCount(uint((len(encoded) - 1) * bitWidth))
-	totalSize := packedSize + delta.Int64SizeBytes + delta.HeaderSize + bitpack.PaddingInt64
-	if cap(dst) < totalSize {
-		dst = make([]byte, totalSize)
+	if _, err := delta.CheckBlock(src, len(dst), delta.Int64SizeBytes); err != nil {
+		return 0, err
 	}
-
-	// Encode the first value as is and bitpack the rest.
-	delta.EncodeHeader(dst, uint16(len(src)), minVal, uint8(bitWidth))
-	binary.LittleEndian.PutUint64(dst[delta.HeaderSize:delta.HeaderSize+delta.Int64SizeBytes], uint64(encoded[0]))
-	bitpack.Pack(dst[delta.HeaderSize+delta.Int64SizeBytes:], unsafecast.Slice[int64](encoded[1:]), uint(bitWidth))
-
-	return dst
+	return DecodeUInt64(dst, src), nil
 }
 
 func DecodeUInt64(dst []uint64, src []byte) uint16 {
@@ -73,50 +49,18 @@ func DecodeUInt64(dst []uint64, src []byte) uint16 {
 		return 1
 	}
 	dst[0] = binary.LittleEndian.Uint64(src[delta.HeaderSize : delta.HeaderSize+delta.Int64SizeBytes])
-	bitpack.Unpack(unsafecast.Slice[int64](dst[1:header.NumValues]), src[delta.HeaderSize+delta.Int64SizeBytes:], uint(header.BitWidth))
-
-	numVals := int(header.NumValues)
-	// Bounds check hint
-	_ = dst[numVals-1]
-
-	// Add minVal to all unpacked values first using SIMD
-	minVal := uint64(header.MinVal)
-	addConstUint64(dst[1:numVals], minVal)
-
-	// Now reconstruct DoD without minVal in critical path
-	d0 := uint64(0)
-	prev := dst[0]
-	i := 1
-
-	// Loop unrolling - process 4 elements at a time
-	for ; i+3 < numVals; i += 4 {
-		d1 := dst[i] + d0
-		prev = d1 + prev
-		dst[i] = prev
-		d0 = d1
-
-		d1 = dst[i+1] + d0
-		prev = d1 + prev
-		dst[i+1] = prev
-		d0 = d1
-
-		d1 = dst[i+2] + d0
-		prev = d1 + prev
-		dst[i+2] = prev
-		d0 = d1
-
-		d1 = dst[i+3] + d0
-		prev = d1 + prev
-		dst[i+3] = prev
-		d0 = d1
+	if header.Regular {
+		delta.Regular[uint64]{Start: dst[0], Interval: uint64(header.MinVal)}.Fill(dst[:header.NumValues])
+		return header.NumValues
 	}
+	delta.UnpackValues(unsafecast.Slice[int64](dst[1:header.NumValues]), src, header, delta.Int64SizeBytes, 0)
 
-	// Handle remaining elements
-	for ; i < numVals; i++ {
-		d1 := dst[i] + d0
-		prev = d1 + prev
-		dst[i] = prev
-		d0 = d1
-	}
+	numVals := int(header.NumValues)
+	// Add minVal to all unpacked values, then reconstruct the deltas and
+	// the values with two prefix sums, which wrap around like unsigned
+	// arithmetic.
+	values := unsafecast.Slice[int64](dst[1:numVals])
+	prefixsum.Int64(values, 0, header.MinVal)
+	prefixsum.Int64(values, int64(dst[0]), 0)
 	return header.NumValues
 }
@@ -0,0 +1,18 @@
+package dod
+
+import "slices"
+
+// Encoder encodes blocks like Encode, EncodeInt64, EncodeInt32 and
+// EncodeUInt64, but keeps the scratch buffer needed during encoding between
+// calls, so that encoding allocates nothing once dst has enough capacity.
+// Generic values are encoded with EncodeWith. The zero value is ready to use.
+// An Encoder must not be used concurrently.
+type Encoder struct {
+	encoded []int64
+}
+
+// scratch returns a scratch buffer of n values.
+func (e *Encoder) scratch(n int) []int64 {
+	e.encoded = slices.Grow(e.encoded[:0], n)[:n]
+	return e.encoded
+}
@@ -0,0 +1,297 @@
+// Package envelope wraps encoded blocks with a small self-describing header
+// holding the codec, its format version and the type of the encoded values,
+// so that persisted blocks can be decoded without knowing how they were
+// written. Writer and Reader frame a sequence of blocks on top of an
+// io.Writer and io.Reader, for series which are too large to encode at once.
+//
+// The header layout is:
+//
+//	magic (2 bytes)
+//	envelope version (uint8)
+//	codec (uint8)
+//	codec format version (uint8)
+//	value type (uint8)
+package envelope
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/fpetkovski/tscodec-go/alp"
+	"github.com/fpetkovski/tscodec-go/delta"
+	"github.com/fpetkovski/tscodec-go/dod"
+)
+
+const (
+	// HeaderSize is the size in bytes of the envelope header.
+	HeaderSize = 2 + 1 + 1 + 1 + 1
+
+	// Version is the version of the envelope header.
+	Version = 1
+)
+
+// magic identifies envelope encoded blocks.
+var magic = [2]byte{'t', 's'}
+
+// Codec identifies the codec of an encoded block. The values are persisted
+// and must never be reused.
+type Codec uint8
+
+const (
+	CodecALP   Codec = 1
+	CodecDelta Codec = 2
+	CodecDoD   Codec = 3
+)
+
+// codecVersions holds the current format version of each codec. A version has
+// to be bumped whenever the format of the codec changes, and decoders of older
+// versions kept around for blocks which have already been persisted. Readers
+// reject versions newer than the ones they know.
+//
+// Version 2 of all codecs adds the checksum flag of AppendChecksum. Version 3
+// of alp adds the stream flag to the metadata of blocks, and version 3 of
+// delta and dod adds patched blocks, with bit widths above 64. Version 4 of
+// delta and dod adds scaled blocks, and version 5 regular blocks.
+var codecVersions = map[Codec]uint8{
+	CodecALP:   3,
+	CodecDelta: 5,
+	CodecDoD:   5,
+}
+
+func (c Codec) String() string {
+	switch c {
+	case CodecALP:
+		return "alp"
+	case CodecDelta:
+		return "delta"
+	case CodecDoD:
+		return "dod"
+	default:
+		return fmt.Sprintf("codec(%d)", uint8(c))
+	}
+}
+
+// ValueType identifies the type of the encoded values. The values are
+// persisted and must never be reused.
+type ValueType uint8
+
+const (
+	ValueTypeFloat64 ValueType = 1
+	ValueTypeFloat32 ValueType = 2
+	ValueTypeInt64   ValueType = 3
+	ValueTypeInt32   ValueType = 4
+	ValueTypeUint64  ValueType = 5
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case ValueTypeFloat64:
+		return "float64"
+	case ValueTypeFloat32:
+		return "float32"
+	case ValueTypeInt64:
+		return "int64"
+	case ValueTypeInt32:
+		return "int32"
+	case ValueTypeUint64:
+		return "uint64"
+	default:
+		return fmt.Sprintf("type(%d)", uint8(t))
+	}
+}
+
+// Value is the set of value types which can be encoded.
+type Value interface {
+	float64 | float32 | int64 | int32 | uint64
+}
+
+var (
+	// ErrCorrupt is returned when the envelope header is truncated or does
+	// not start with the expected magic bytes. Errors found by the codecs
+	// are returned as is.
+	ErrCorrupt = errors.New("corrupt envelope")
+	// ErrUnsupported is returned for unknown codecs, versions and for value
+	// types which the codec cannot encode.
+	ErrUnsupported = errors.New("unsupported")
+	// ErrValueType is returned when decoding values into a different type
+	// than they were encoded from.
+	ErrValueType = errors.New("value type mismatch")
+)
+
+// Header is the decoded envelope header.
+type Header struct {
+	Version      uint8
+	Codec        Codec
+	CodecVersion uint8
+	ValueType    ValueType
+}
+
+// Encode encodes src with the codec and wraps it in an envelope. ALP encodes
+// float64 and float32 values, delta int64 and int32 values and dod int64,
+// int32 and uint64 values. Like the codecs, Encode reuses the capacity of dst.
+func Encode[T Value](dst []byte, codec Codec, src []T) ([]byte, error) {
+	version, ok := codecVersions[codec]
+	if !ok {
+		return dst[:0], fmt.Errorf("%w: %s", ErrUnsupported, codec)
+	}
+	valueType := valueTypeOf[T]()
+	if codec == CodecDelta || codec == CodecDoD {
+		if len(src) > delta.Int64BlockSize {
+			return dst[:0], fmt.Errorf("%w: %s encodes at most %d values", ErrUnsupported, codec, delta.Int64BlockSize)
+		}
+	}
+
+	if cap(dst) < HeaderSize {
+		dst = make([]byte, HeaderSize)
+	}
+	dst = dst[:HeaderSize]
+	EncodeHeader(dst, Header{
+		Version:      Version,
+		Codec:        codec,
+		CodecVersion: version,
+		ValueType:    valueType,
+	})
+
+	body := dst[HeaderSize:HeaderSize]
+	payload, err := encodePayload(body, codec, src)
+	if err != nil {
+		return dst[:0], err
+	}
+	if len(payload) > 0 && cap(body) > 0 && &payload[0] == &body[:1][0] {
+		// The payload was encoded in place.
+		return dst[:HeaderSize+len(payload)], nil
+	}
+	return append(dst, payload...), nil
+}
+
+func encodePayload[T Value](dst []byte, codec Codec, src []T) ([]byte, error) {
+	switch codec {
+	case CodecALP:
+		switch src := any(src).(type) {
+		case []float64:
+			return alp.Encode(dst, src), nil
+		case []float32:
+			return alp.EncodeFloat32(dst, src), nil
+		}
+	case CodecDelta:
+		switch src := any(src).(type) {
+		case []int64:
+			return delta.EncodeInt64(dst, src), nil
+		case []int32:
+			return delta.EncodeInt32(dst, src), nil
+		}
+	case CodecDoD:
+		switch src := any(src).(type) {
+		case []int64:
+			return dod.EncodeInt64(dst, src), nil
+		case []int32:
+			return dod.EncodeInt32(dst, src), nil
+		case []uint64:
+			return dod.EncodeUInt64(dst, src), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s cannot encode %s values", ErrUnsupported, codec, valueTypeOf[T]())
+}
+
+// Decode decodes an envelope into dst using the codec recorded in its header.
+// The capacity of dst has to fit all encoded values, and T has to match the
+// type of the encoded values. The payload is validated by the checked decoders
+// of the codecs, so corrupted input returns an error instead of panicking.
+func Decode[T Value](dst []T, data []byte) ([]T, error) {
+	header, err := DecodeHeader(data)
+	if err != nil {
+		return dst[:0], err
+	}
+	if header.ValueType != valueTypeOf[T]() {
+		return dst[:0], fmt.Errorf("%w: cannot decode %s values into %s", ErrValueType, header.ValueType, valueTypeOf[T]())
+	}
+	// The decoders of the current version decode all older versions, whose
+	// formats are a subset of it.
+	if version, ok := codecVersions[header.Codec]; !ok || header.CodecVersion == 0 || header.CodecVersion > version {
+		return dst[:0], fmt.Errorf("%w: %s version %d", ErrUnsupported, header.Codec, header.CodecVersion)
+	}
+
+	var (
+		payload = data[HeaderSize:]
+		n       uint16
+	)
+	switch header.Codec {
+	case CodecALP:
+		switch dst := any(dst).(type) {
+		case []float64:
+			values, err := alp.DecodeChecked(dst, payload)
+			return any(values).([]T), err
+		case []float32:
+			values, err := alp.DecodeFloat32Checked(dst, payload)
+			return any(values).([]T), err
+		}
+	case CodecDelta:
+		switch dst := any(dst[:cap(dst)]).(type) {
+		case []int64:
+			n, err = delta.DecodeInt64Checked(dst, payload)
+			return any(dst[:n]).([]T), err
+		case []int32:
+			n, err = delta.DecodeInt32Checked(dst, payload)
+			return any(dst[:n]).([]T), err
+		}
+	case CodecDoD:
+		switch dst := any(dst[:cap(dst)]).(type) {
+		case []int64:
+			n, err = dod.DecodeInt64Checked(dst, payload)
+			return any(dst[:n]).([]T), err
+		case []int32:
+			n, err = dod.DecodeInt32Checked(dst, payload)
+			return any(dst[:n]).([]T), err
+		case []uint64:
+			n, err = dod.DecodeUInt64Checked(dst, payload)
+			return any(dst[:n]).([]T), err
+		}
+	}
+	return dst[:0], fmt.Errorf("%w: %s cannot decode %s values", ErrUnsupported, header.Codec, header.ValueType)
+}
+
+// EncodeHeader writes the envelope header to the first HeaderSize bytes of dst.
+func EncodeHeader(dst []byte, header Header) {
+	copy(dst, magic[:])
+	dst[2] = header.Version
+	dst[3] = byte(header.Codec)
+	dst[4] = header.CodecVersion
+	dst[5] = byte(header.ValueType)
+}
+
+// DecodeHeader decodes the envelope header at the start of data.
+func DecodeHeader(data []byte) (Header, error) {
+	if len(data) < HeaderSize {
+		return Header{}, fmt.Errorf("%w: %d bytes are too short for a header", ErrCorrupt, len(data))
+	}
+	if data[0] != magic[0] || data[1] != magic[1] {
+		return Header{}, fmt.Errorf("%w: invalid magic %x", ErrCorrupt, data[:2])
+	}
+	header := Header{
+		Version:      data[2],
+		Codec:        Codec(data[3]),
+		CodecVersion: data[4],
+		ValueType:    ValueType(data[5]),
+	}
+	if header.Version != Version {
+		return header, fmt.Errorf("%w: envelope version %d", ErrUnsupported, header.Version)
+	}
+	return header, nil
+}
+
+// valueTypeOf returns the ValueType of T.
+func valueTypeOf[T Value]() ValueType {
+	var zero T
+	switch any(zero).(type) {
+	case float64:
+		return ValueTypeFloat64
+	case float32:
+		return ValueTypeFloat32
+	case int64:
+		return ValueTypeInt64
+	case int32:
+		return ValueTypeInt32
+	default:
+		return ValueTypeUint64
+	}
+}
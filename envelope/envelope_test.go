@@ -0,0 +1,264 @@
+package envelope
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/fpetkovski/tscodec-go/alp"
+	"github.com/fpetkovski/tscodec-go/delta"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	t.Run("alp float64", func(t *testing.T) {
+		testRoundTrip(t, CodecALP, []float64{1.5, 2.25, 3.125, 100.5})
+	})
+	t.Run("alp float32", func(t *testing.T) {
+		testRoundTrip(t, CodecALP, []float32{1.5, 2.25, 3.125, 100.5})
+	})
+	t.Run("delta int64", func(t *testing.T) {
+		testRoundTrip(t, CodecDelta, []int64{10, 15, 22, 31, 55})
+	})
+	t.Run("delta int32", func(t *testing.T) {
+		testRoundTrip(t, CodecDelta, []int32{10, -15, 22, 31, 55})
+	})
+	t.Run("delta empty", func(t *testing.T) {
+		testRoundTrip(t, CodecDelta, []int64{})
+	})
+	t.Run("dod int64", func(t *testing.T) {
+		testRoundTrip(t, CodecDoD, []int64{1000, 2000, 3000, 4001, 5003})
+	})
+	t.Run("dod int32", func(t *testing.T) {
+		testRoundTrip(t, CodecDoD, []int32{1000, 2000, 3000, 4001, 5003})
+	})
+	t.Run("dod uint64", func(t *testing.T) {
+		testRoundTrip(t, CodecDoD, []uint64{1 << 63, 1<<63 + 10, 1<<63 + 20})
+	})
+}
+
+func testRoundTrip[T Value](t *testing.T, codec Codec, src []T) {
+	t.Helper()
+
+	// Encode into a buffer which is large enough and into one which is not.
+	for _, buf := range [][]byte{make([]byte, 0, 1024), nil} {
+		encoded, err := Encode(buf, codec, src)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		header, err := DecodeHeader(encoded)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if header.Codec != codec || header.ValueType != valueTypeOf[T]() {
+			t.Fatalf("unexpected header %+v", header)
+		}
+
+		decoded, err := Decode(make([]T, 0, delta.Int64BlockSize), encoded)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(decoded, src) {
+			t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded, src)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	encoded, err := Encode(nil, CodecALP, []float64{1.5, 2.5, 3.5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	corrupt := func(f func([]byte)) []byte {
+		data := slices.Clone(encoded)
+		f(data)
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		cap  int
+		err  error
+	}{
+		{name: "short", data: encoded[:HeaderSize-1], cap: 3, err: ErrCorrupt},
+		{name: "magic", data: corrupt(func(b []byte) { b[0] = 'x' }), cap: 3, err: ErrCorrupt},
+		{name: "version", data: corrupt(func(b []byte) { b[2] = Version + 1 }), cap: 3, err: ErrUnsupported},
+		{name: "codec", data: corrupt(func(b []byte) { b[3] = 42 }), cap: 3, err: ErrUnsupported},
+		{name: "codec version", data: corrupt(func(b []byte) { b[4] = 42 }), cap: 3, err: ErrUnsupported},
+		{name: "newer codec version", data: corrupt(func(b []byte) { b[4] = codecVersions[CodecALP] + 1 }), cap: 3, err: ErrUnsupported},
+		{name: "zero codec version", data: corrupt(func(b []byte) { b[4] = 0 }), cap: 3, err: ErrUnsupported},
+		{name: "older codec version", data: corrupt(func(b []byte) { b[4] = 1 }), cap: 3},
+		{name: "value type", data: corrupt(func(b []byte) { b[5] = byte(ValueTypeFloat32) }), cap: 3, err: ErrValueType},
+		{name: "truncated payload", data: encoded[:len(encoded)-10], cap: 3, err: alp.ErrCorrupt},
+		{name: "short buffer", data: encoded, cap: 2, err: alp.ErrShortBuffer},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Decode(make([]float64, 0, tc.cap), tc.data); !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+		})
+	}
+
+	if _, err := Decode(make([]int64, 3), encoded); !errors.Is(err, ErrValueType) {
+		t.Fatalf("expected ErrValueType, got %v", err)
+	}
+	if _, err := Encode(nil, CodecALP, []int64{1, 2}); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+	if _, err := Encode(nil, CodecDelta, []uint64{1, 2}); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+	if _, err := Encode(nil, CodecDoD, make([]int64, delta.Int64BlockSize+1)); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, codec := range []Codec{CodecDelta, CodecDoD} {
+		encoded, _ := Encode(nil, codec, []int64{1000, 2000, 3000, 4001, 5003})
+		f.Add(encoded)
+	}
+	encoded, _ := Encode(nil, CodecALP, []float64{1.5, 2.25, 3.125, 100.5})
+	f.Add(encoded)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = Decode(make([]float64, 0, 64), data)
+		_, _ = Decode(make([]float32, 0, 64), data)
+		_, _ = Decode(make([]int64, 0, delta.Int64BlockSize), data)
+		_, _ = Decode(make([]int32, 0, delta.Int64BlockSize), data)
+		_, _ = Decode(make([]uint64, 0, delta.Int64BlockSize), data)
+	})
+}
+
+func TestStream(t *testing.T) {
+	t.Run("alp float64", func(t *testing.T) {
+		values := make([]float64, 2500)
+		for i := range values {
+			values[i] = float64(i%97) / 4
+		}
+		testStream(t, Options{Codec: CodecALP}, values)
+	})
+	t.Run("alp float32 checksum", func(t *testing.T) {
+		values := make([]float32, 300)
+		for i := range values {
+			values[i] = float32(i) / 8
+		}
+		testStream(t, Options{Codec: CodecALP, BlockSize: 64, Checksum: true}, values)
+	})
+	t.Run("delta int32", func(t *testing.T) {
+		values := make([]int32, 1000)
+		for i := range values {
+			values[i] = int32(i*i) - 5000
+		}
+		testStream(t, Options{Codec: CodecDelta, BlockSize: 100}, values)
+	})
+	t.Run("dod int64 checksum", func(t *testing.T) {
+		values := make([]int64, 10000)
+		for i := range values {
+			values[i] = 1700000000000 + int64(i)*15000 + int64(i%7)
+		}
+		testStream(t, Options{Codec: CodecDoD, BlockSize: delta.Int64BlockSize, Checksum: true}, values)
+	})
+	t.Run("empty", func(t *testing.T) {
+		testStream(t, Options{Codec: CodecDoD}, []uint64{})
+	})
+}
+
+func testStream[T Value](t *testing.T, opts Options, values []T) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter[T](&buf, opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// Write in chunks which do not line up with the blocks.
+	for src := values; len(src) > 0; {
+		n := min(len(src), 37)
+		if written, err := w.Write(src[:n]); err != nil || written != n {
+			t.Fatalf("unexpected write of %d values: %v", written, err)
+		}
+		src = src[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := w.Write(values[:0:0]); err == nil {
+		t.Fatalf("expected error writing to a closed writer")
+	}
+
+	r := NewReader[T](&buf)
+	var (
+		decoded []T
+		dst     = make([]T, 50)
+	)
+	for {
+		n, err := r.Read(dst)
+		decoded = append(decoded, dst[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if !slices.Equal(decoded, values) {
+		t.Fatalf("Slices are not equal: got: [%v] want: [%v]", decoded, values)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	if _, err := NewWriter[int64](io.Discard, Options{Codec: CodecALP}); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+	if _, err := NewWriter[int64](io.Discard, Options{Codec: CodecDoD, BlockSize: delta.Int64BlockSize + 1}); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter[float64](&buf, Options{Codec: CodecALP, BlockSize: 4, Checksum: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := w.Write([]float64{1.5, 2.5, 3.5, 4.5, 5.5, 6.5, 7.5, 8.5}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	stream := buf.Bytes()
+	frameSize := len(stream) / 2
+
+	corrupt := func(f func([]byte)) []byte {
+		data := slices.Clone(stream)
+		f(data)
+		return data
+	}
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{name: "truncated header", data: stream[:frameSize+3], err: io.ErrUnexpectedEOF},
+		{name: "truncated frame", data: stream[:frameSize+FrameHeaderSize+5], err: io.ErrUnexpectedEOF},
+		{name: "frame size", data: corrupt(func(b []byte) { b[frameSize+3] = 0xff }), err: ErrCorrupt},
+		{name: "value count", data: corrupt(func(b []byte) { b[frameSize+4] = 5 }), err: ErrCorrupt},
+		{name: "checksum", data: corrupt(func(b []byte) { b[len(b)-1] ^= 1 }), err: alp.ErrChecksum},
+		{name: "value type", data: corrupt(func(b []byte) { b[frameSize+FrameHeaderSize+5] = byte(ValueTypeInt64) }), err: ErrValueType},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewReader[float64](bytes.NewReader(tc.data))
+			dst := make([]float64, 8)
+			// The first frame is intact and returned before the error.
+			if n, err := r.Read(dst); n != 4 || err != nil {
+				t.Fatalf("unexpected read of %d values: %v", n, err)
+			}
+			for range 2 {
+				if _, err := r.Read(dst); !errors.Is(err, tc.err) {
+					t.Fatalf("expected error %v, got %v", tc.err, err)
+				}
+			}
+		})
+	}
+}
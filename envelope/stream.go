@@ -0,0 +1,252 @@
+package envelope
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/fpetkovski/tscodec-go/alp"
+	"github.com/fpetkovski/tscodec-go/delta"
+	"github.com/fpetkovski/tscodec-go/dod"
+)
+
+// A framed stream is a sequence of frames, each holding one envelope block:
+//
+//	frame length in bytes, excluding the frame header (uint32)
+//	value count (uint32)
+//	envelope block
+//
+// Frames are written as soon as a block is full, and read one at a time, so
+// only a single block is held in memory on either side.
+
+const (
+	// FrameHeaderSize is the size in bytes of the header preceding each frame.
+	FrameHeaderSize = 4 + 4
+
+	// DefaultBlockSize is the number of values per frame if Options does not
+	// set one.
+	DefaultBlockSize = 1024
+
+	// MaxFrameSize is the largest frame a Reader accepts. It bounds the memory
+	// allocated for corrupted frame headers, and is well above the size of
+	// the largest block any codec produces.
+	MaxFrameSize = 1 << 21
+)
+
+var errClosed = errors.New("envelope: writer is closed")
+
+// Options configures a Writer.
+type Options struct {
+	// Codec encodes the blocks of the stream.
+	Codec Codec
+	// BlockSize is the number of values encoded in each frame. It defaults
+	// to DefaultBlockSize and is limited by the codec: ALP encodes up to
+	// alp.MaxStreamBlockSize values and delta and dod up to
+	// delta.Int64BlockSize values per block.
+	BlockSize int
+	// Checksum appends a CRC32C trailer to each block, see AppendChecksum in
+	// the codec packages.
+	Checksum bool
+}
+
+// maxBlockSize returns the maximum number of values per frame of the codec.
+func maxBlockSize(codec Codec) int {
+	if codec == CodecALP {
+		return alp.MaxStreamBlockSize
+	}
+	return delta.Int64BlockSize
+}
+
+// Writer encodes values into a framed stream. Values are buffered until a
+// block is full, which is then encoded and written to the underlying writer.
+type Writer[T Value] struct {
+	w      io.Writer
+	opts   Options
+	values []T
+	frame  []byte
+	err    error
+}
+
+// NewWriter returns a Writer encoding values with the codec of opts into w.
+// It returns an error if the codec cannot encode values of type T or the
+// block size is out of range.
+func NewWriter[T Value](w io.Writer, opts Options) (*Writer[T], error) {
+	if opts.BlockSize == 0 {
+		opts.BlockSize = DefaultBlockSize
+	}
+	// Encoding no values checks that the codec supports T.
+	if _, err := encodePayload(nil, opts.Codec, []T(nil)); err != nil {
+		return nil, err
+	}
+	if opts.BlockSize < 1 || opts.BlockSize > maxBlockSize(opts.Codec) {
+		return nil, fmt.Errorf("%w: block size %d for %s must be between 1 and %d", ErrUnsupported, opts.BlockSize, opts.Codec, maxBlockSize(opts.Codec))
+	}
+	return &Writer[T]{
+		w:      w,
+		opts:   opts,
+		values: make([]T, 0, opts.BlockSize),
+	}, nil
+}
+
+// Reset discards buffered values and errors and writes further frames to w.
+func (w *Writer[T]) Reset(out io.Writer) {
+	w.w = out
+	w.values = w.values[:0]
+	w.err = nil
+}
+
+// Write buffers values and writes a frame for every block which fills up. It
+// returns the number of values consumed, which is less than len(values) only
+// if writing a frame failed. Errors are sticky.
+func (w *Writer[T]) Write(values []T) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n := 0
+	for len(values) > 0 {
+		c := min(len(values), w.opts.BlockSize-len(w.values))
+		w.values = append(w.values, values[:c]...)
+		values = values[c:]
+		n += c
+		if len(w.values) == w.opts.BlockSize {
+			if err := w.writeFrame(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Flush writes the buffered values as a frame, even if the block is not full.
+func (w *Writer[T]) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.values) == 0 {
+		return nil
+	}
+	return w.writeFrame()
+}
+
+// Close flushes the buffered values. Writes after Close return an error. The
+// underlying writer is not closed.
+func (w *Writer[T]) Close() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	w.err = errClosed
+	return nil
+}
+
+func (w *Writer[T]) writeFrame() error {
+	frame := slices.Grow(w.frame[:0], FrameHeaderSize)[:FrameHeaderSize]
+	block, err := Encode(frame[FrameHeaderSize:], w.opts.Codec, w.values)
+	if err != nil {
+		w.err = err
+		return err
+	}
+	if w.opts.Checksum {
+		payload := appendChecksum(w.opts.Codec, block[HeaderSize:])
+		block = append(block[:HeaderSize], payload...)
+	}
+	// Append is a no-op copy if the block was encoded in place.
+	frame = append(frame, block...)
+	binary.LittleEndian.PutUint32(frame, uint32(len(block)))
+	binary.LittleEndian.PutUint32(frame[4:], uint32(len(w.values)))
+
+	w.frame = frame
+	w.values = w.values[:0]
+	if _, err := w.w.Write(frame); err != nil {
+		w.err = err
+		return err
+	}
+	return nil
+}
+
+func appendChecksum(codec Codec, payload []byte) []byte {
+	switch codec {
+	case CodecALP:
+		return alp.AppendChecksum(payload)
+	case CodecDoD:
+		return dod.AppendChecksum(payload)
+	default:
+		return delta.AppendChecksum(payload)
+	}
+}
+
+// Reader decodes values from a framed stream written by a Writer. Frames are
+// read and decoded one at a time as values are consumed.
+type Reader[T Value] struct {
+	r      io.Reader
+	frame  []byte
+	values []T
+	offset int
+	err    error
+}
+
+// NewReader returns a Reader decoding values of type T from r.
+func NewReader[T Value](r io.Reader) *Reader[T] {
+	return &Reader[T]{r: r}
+}
+
+// Reset discards buffered values and errors and reads further frames from r.
+func (r *Reader[T]) Reset(in io.Reader) {
+	r.r = in
+	r.values = r.values[:0]
+	r.offset = 0
+	r.err = nil
+}
+
+// Read decodes up to len(dst) values into dst and returns the number of
+// values read. At the end of the stream it returns io.EOF, and
+// io.ErrUnexpectedEOF if the stream ends within a frame. Corrupted frames
+// return the errors of Decode. Errors are sticky.
+func (r *Reader[T]) Read(dst []T) (int, error) {
+	n := 0
+	for n < len(dst) && r.err == nil {
+		if r.offset == len(r.values) {
+			r.err = r.readFrame()
+			continue
+		}
+		c := copy(dst[n:], r.values[r.offset:])
+		r.offset += c
+		n += c
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return 0, r.err
+}
+
+func (r *Reader[T]) readFrame() error {
+	var header [FrameHeaderSize]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		return err
+	}
+	var (
+		size  = binary.LittleEndian.Uint32(header[:])
+		count = binary.LittleEndian.Uint32(header[4:])
+	)
+	if size > MaxFrameSize || count > alp.MaxStreamBlockSize {
+		return fmt.Errorf("%w: frame of %d bytes with %d values", ErrCorrupt, size, count)
+	}
+	r.frame = slices.Grow(r.frame[:0], int(size))[:size]
+	if _, err := io.ReadFull(r.r, r.frame); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	values, err := Decode(slices.Grow(r.values[:0], int(count)), r.frame)
+	if err != nil {
+		return err
+	}
+	if len(values) != int(count) {
+		return fmt.Errorf("%w: frame holds %d values instead of %d", ErrCorrupt, len(values), count)
+	}
+	r.values, r.offset = values, 0
+	return nil
+}
@@ -7,4 +7,4 @@ require (
 	github.com/parquet-go/bitpack v0.1.1-0.20251029180122-fa1aca9bf2d1
 )
 
-require golang.org/x/sys v0.37.0 // indirect
+require golang.org/x/sys v0.37.0
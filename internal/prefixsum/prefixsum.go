@@ -0,0 +1,30 @@
+// Package prefixsum computes the running sums which reconstruct delta encoded
+// values. On amd64 the sums are computed with AVX2 or AVX-512 if the CPU
+// supports them, and on arm64 the constant is added with NEON.
+package prefixsum
+
+// Int64 adds c to each value of dst and replaces it with the running sum of
+// the values up to and including it, starting from base:
+//
+//	dst[i] = base + (dst[0] + c) + ... + (dst[i] + c)
+//
+// Sums wrap around on overflow.
+func Int64(dst []int64, base, c int64) {
+	int64Arch(dst, base, c)
+}
+
+func int64Generic(dst []int64, base, c int64) {
+	i := 0
+	// Adding c first keeps it out of the dependency chain of the sums.
+	for ; i+3 < len(dst); i += 4 {
+		dst[i] = dst[i] + c + base
+		dst[i+1] = dst[i+1] + c + dst[i]
+		dst[i+2] = dst[i+2] + c + dst[i+1]
+		dst[i+3] = dst[i+3] + c + dst[i+2]
+		base = dst[i+3]
+	}
+	for ; i < len(dst); i++ {
+		dst[i] = dst[i] + c + base
+		base = dst[i]
+	}
+}
@@ -0,0 +1,36 @@
+//go:build amd64 && !purego
+
+package prefixsum
+
+import "golang.org/x/sys/cpu"
+
+var (
+	hasAVX2   = cpu.X86.HasAVX2
+	hasAVX512 = cpu.X86.HasAVX512F
+)
+
+// int64AVX2 computes the running sums of dst, whose length must be a multiple
+// of 8, and returns the last sum.
+//
+//go:noescape
+func int64AVX2(dst []int64, base, c int64) int64
+
+// int64AVX512 computes the running sums of dst, whose length must be a
+// multiple of 16, and returns the last sum.
+//
+//go:noescape
+func int64AVX512(dst []int64, base, c int64) int64
+
+func int64Arch(dst []int64, base, c int64) {
+	switch {
+	case hasAVX512:
+		n := len(dst) &^ 15
+		base = int64AVX512(dst[:n], base, c)
+		dst = dst[n:]
+	case hasAVX2:
+		n := len(dst) &^ 7
+		base = int64AVX2(dst[:n], base, c)
+		dst = dst[n:]
+	}
+	int64Generic(dst, base, c)
+}
@@ -0,0 +1,126 @@
+//go:build amd64 && !purego
+
+#include "textflag.h"
+
+// Each vector is first summed locally by adding copies of itself shifted by
+// 1, 2 (and 4) lanes. The running sum of all previous vectors is added
+// afterwards, so that only a single add per iteration depends on the
+// previous iteration.
+
+// func int64AVX2(dst []int64, base, c int64) int64
+TEXT ·int64AVX2(SB), NOSPLIT, $0-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ dst_len+8(FP), CX
+	MOVQ base+24(FP), AX
+	MOVQ c+32(FP), BX
+
+	SHRQ $3, CX
+	JZ   avx2_empty
+
+	MOVQ         AX, X0
+	VPBROADCASTQ X0, Y0 // running sum
+	MOVQ         BX, X1
+	VPBROADCASTQ X1, Y1 // constant
+	VPXOR        Y7, Y7, Y7 // zero
+
+avx2_loop:
+	VPADDQ (DI), Y1, Y2
+	VPADDQ 32(DI), Y1, Y3
+
+	// Shift by one lane and add.
+	VPERMQ   $0x90, Y2, Y4
+	VPERMQ   $0x90, Y3, Y5
+	VPBLENDD $0x03, Y7, Y4, Y4
+	VPBLENDD $0x03, Y7, Y5, Y5
+	VPADDQ   Y4, Y2, Y2
+	VPADDQ   Y5, Y3, Y3
+
+	// Shift by two lanes and add.
+	VPERMQ   $0x40, Y2, Y4
+	VPERMQ   $0x40, Y3, Y5
+	VPBLENDD $0x0f, Y7, Y4, Y4
+	VPBLENDD $0x0f, Y7, Y5, Y5
+	VPADDQ   Y4, Y2, Y2
+	VPADDQ   Y5, Y3, Y3
+
+	// Carry the sum of the first vector into the second one.
+	VPERMQ $0xff, Y2, Y4
+	VPADDQ Y4, Y3, Y3
+	VPERMQ $0xff, Y3, Y5
+
+	VPADDQ  Y0, Y2, Y2
+	VPADDQ  Y0, Y3, Y3
+	VPADDQ  Y5, Y0, Y0
+	VMOVDQU Y2, (DI)
+	VMOVDQU Y3, 32(DI)
+
+	ADDQ $64, DI
+	DECQ CX
+	JNZ  avx2_loop
+
+	MOVQ       X0, AX
+	VZEROUPPER
+
+avx2_empty:
+	MOVQ AX, ret+40(FP)
+	RET
+
+// func int64AVX512(dst []int64, base, c int64) int64
+TEXT ·int64AVX512(SB), NOSPLIT, $0-48
+	MOVQ dst_base+0(FP), DI
+	MOVQ dst_len+8(FP), CX
+	MOVQ base+24(FP), AX
+	MOVQ c+32(FP), BX
+
+	SHRQ $4, CX
+	JZ   avx512_empty
+
+	VPBROADCASTQ AX, Z0 // running sum
+	VPBROADCASTQ BX, Z1 // constant
+	VPXORQ       Z7, Z7, Z7 // zero
+	MOVQ         $7, DX
+	VPBROADCASTQ DX, Z6 // permutation broadcasting the last lane
+
+avx512_loop:
+	VPADDQ (DI), Z1, Z2
+	VPADDQ 64(DI), Z1, Z3
+
+	// Shift by one lane and add.
+	VALIGNQ $7, Z7, Z2, Z4
+	VALIGNQ $7, Z7, Z3, Z5
+	VPADDQ  Z4, Z2, Z2
+	VPADDQ  Z5, Z3, Z3
+
+	// Shift by two lanes and add.
+	VALIGNQ $6, Z7, Z2, Z4
+	VALIGNQ $6, Z7, Z3, Z5
+	VPADDQ  Z4, Z2, Z2
+	VPADDQ  Z5, Z3, Z3
+
+	// Shift by four lanes and add.
+	VALIGNQ $4, Z7, Z2, Z4
+	VALIGNQ $4, Z7, Z3, Z5
+	VPADDQ  Z4, Z2, Z2
+	VPADDQ  Z5, Z3, Z3
+
+	// Carry the sum of the first vector into the second one.
+	VPERMQ Z2, Z6, Z4
+	VPADDQ Z4, Z3, Z3
+	VPERMQ Z3, Z6, Z5
+
+	VPADDQ    Z0, Z2, Z2
+	VPADDQ    Z0, Z3, Z3
+	VPADDQ    Z5, Z0, Z0
+	VMOVDQU64 Z2, (DI)
+	VMOVDQU64 Z3, 64(DI)
+
+	ADDQ $128, DI
+	DECQ CX
+	JNZ  avx512_loop
+
+	VMOVQ      X0, AX
+	VZEROUPPER
+
+avx512_empty:
+	MOVQ AX, ret+40(FP)
+	RET
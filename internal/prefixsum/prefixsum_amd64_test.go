@@ -0,0 +1,14 @@
+//go:build amd64 && !purego
+
+package prefixsum
+
+import "testing"
+
+func TestInt64AVX2(t *testing.T) {
+	if !hasAVX2 {
+		t.Skip("AVX2 is not supported")
+	}
+	defer func(avx512 bool) { hasAVX512 = avx512 }(hasAVX512)
+	hasAVX512 = false
+	testInt64(t, Int64)
+}
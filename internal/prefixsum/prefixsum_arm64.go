@@ -0,0 +1,13 @@
+//go:build arm64 && !purego
+
+package prefixsum
+
+// addConstInt64 adds a constant to all elements in the slice using SIMD
+func addConstInt64(dst []int64, c int64)
+
+func int64Arch(dst []int64, base, c int64) {
+	if c != 0 {
+		addConstInt64(dst, c)
+	}
+	int64Generic(dst, base, 0)
+}
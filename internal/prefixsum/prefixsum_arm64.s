@@ -1,4 +1,4 @@
-//go:build arm64
+//go:build arm64 && !purego
 
 #include "textflag.h"
 
@@ -95,82 +95,3 @@ tail1:
 
 done:
     RET
-
-// func addConstInt32(dst []int32, c int32)
-TEXT ·addConstInt32(SB), NOSPLIT, $0-28
-    MOVD dst_base+0(FP), R0
-    MOVD dst_len+8(FP), R1
-    MOVW c+24(FP), R2
-
-    CBZ R1, done32
-
-    // Duplicate constant to vector register (4x int32)
-    VMOV R2, V0.S[0]
-    VMOV R2, V0.S[1]
-    VMOV R2, V0.S[2]
-    VMOV R2, V0.S[3]
-
-    // Main loop - process 16 elements at a time using SIMD
-    CMP $16, R1
-    BLT tail32_8
-
-loop32_16:
-    // Prefetch next cache line
-    PRFM 64(R0), PLDL1KEEP
-
-    // Load 16 int32 values (4 vectors of 4 int32 each)
-    VLD1 (R0), [V1.S4, V2.S4, V3.S4, V4.S4]
-
-    // Add constant vector to each
-    VADD V0.S4, V1.S4, V1.S4
-    VADD V0.S4, V2.S4, V2.S4
-    VADD V0.S4, V3.S4, V3.S4
-    VADD V0.S4, V4.S4, V4.S4
-
-    // Store back
-    VST1 [V1.S4, V2.S4, V3.S4, V4.S4], (R0)
-
-    ADD $64, R0
-    SUB $16, R1
-    CMP $16, R1
-    BGE loop32_16
-
-tail32_8:
-    CMP $8, R1
-    BLT tail32_4
-
-    // Load 8 int32 values (2 vectors)
-    VLD1 (R0), [V1.S4, V2.S4]
-    VADD V0.S4, V1.S4, V1.S4
-    VADD V0.S4, V2.S4, V2.S4
-    VST1 [V1.S4, V2.S4], (R0)
-    ADD $32, R0
-    SUB $8, R1
-
-tail32_4:
-    CMP $4, R1
-    BLT tail32_1
-
-    // Load 4 int32 values (1 vector)
-    VLD1 (R0), [V1.S4]
-    VADD V0.S4, V1.S4, V1.S4
-    VST1 [V1.S4], (R0)
-    ADD $16, R0
-    SUB $4, R1
-
-tail32_1:
-    CBZ R1, done32
-    MOVW (R0), R3
-    ADD R2, R3
-    MOVW R3, (R0)
-    ADD $4, R0
-    SUB $1, R1
-    B tail32_1
-
-done32:
-    RET
-
-// func addConstUint64(dst []uint64, c uint64)
-TEXT ·addConstUint64(SB), NOSPLIT, $0-32
-    // Same as int64 version
-    JMP ·addConstInt64(SB)
@@ -0,0 +1,7 @@
+//go:build !(amd64 || arm64) || purego
+
+package prefixsum
+
+func int64Arch(dst []int64, base, c int64) {
+	int64Generic(dst, base, c)
+}
@@ -0,0 +1,59 @@
+package prefixsum
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func reference(dst []int64, base, c int64) []int64 {
+	result := make([]int64, len(dst))
+	for i, v := range dst {
+		base += v + c
+		result[i] = base
+	}
+	return result
+}
+
+func testInt64(t *testing.T, prefixSum func([]int64, int64, int64)) {
+	t.Helper()
+	gen := rand.New(rand.NewSource(42))
+	for n := range 100 {
+		for _, c := range []int64{0, -3, math.MaxInt64} {
+			src := make([]int64, n)
+			for i := range src {
+				src[i] = gen.Int63() - math.MaxInt64/2
+			}
+			base := gen.Int63()
+			want := reference(src, base, c)
+			prefixSum(src, base, c)
+			if !slices.Equal(src, want) {
+				t.Fatalf("length %d: got %v, want %v", n, src, want)
+			}
+		}
+	}
+}
+
+func TestInt64(t *testing.T) {
+	testInt64(t, Int64)
+	testInt64(t, int64Generic)
+}
+
+func FuzzInt64(f *testing.F) {
+	f.Add(uint8(17), int64(0), int64(1), int64(-1))
+	f.Add(uint8(100), int64(math.MaxInt64), int64(math.MinInt64), int64(5))
+
+	f.Fuzz(func(t *testing.T, size uint8, seed, base, c int64) {
+		src := make([]int64, size)
+		gen := rand.New(rand.NewSource(seed))
+		for i := range src {
+			src[i] = int64(gen.Uint64())
+		}
+		want := reference(src, base, c)
+		Int64(src, base, c)
+		if !slices.Equal(src, want) {
+			t.Fatalf("got %v, want %v", src, want)
+		}
+	})
+}
@@ -0,0 +1,228 @@
+// Package promchunk adapts the chunk format of tscodec-go, delta-of-delta
+// timestamps and ALP values, to the chunkenc interfaces of Prometheus.
+//
+// Appended samples are buffered in memory and only encoded when the chunk is
+// compacted or its bytes are requested, since the codecs encode whole blocks
+// at once.
+package promchunk
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+
+	"github.com/fpetkovski/tscodec-go/chunk"
+)
+
+// EncALPDoD is the chunk encoding of ALP and delta-of-delta chunks. It is
+// outside of the range of encodings used by Prometheus.
+const EncALPDoD chunkenc.Encoding = 128
+
+// Chunk is a chunkenc.Chunk storing timestamps with delta-of-delta and values
+// with ALP. A chunk holds at most chunk.MaxSamples samples.
+type Chunk struct {
+	data []byte
+	ts   []int64
+	vs   []float64
+	// loaded is set when ts and vs hold the samples of data.
+	loaded bool
+	// dirty is set when ts and vs hold samples which are not encoded in data.
+	dirty bool
+	err   error
+}
+
+// NewChunk returns a new empty chunk.
+func NewChunk() *Chunk {
+	return &Chunk{loaded: true}
+}
+
+// Bytes returns the encoded chunk, encoding any buffered samples first.
+func (c *Chunk) Bytes() []byte {
+	c.encode()
+	return c.data
+}
+
+// Encoding returns EncALPDoD.
+func (c *Chunk) Encoding() chunkenc.Encoding {
+	return EncALPDoD
+}
+
+// Appender returns an appender which buffers samples in the chunk.
+func (c *Chunk) Appender() (chunkenc.Appender, error) {
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+	return &appender{c: c}, nil
+}
+
+// NumSamples returns the number of samples in the chunk, or 0 if the chunk
+// cannot be decoded.
+func (c *Chunk) NumSamples() int {
+	if err := c.load(); err != nil {
+		return 0
+	}
+	return len(c.ts)
+}
+
+// Compact encodes the buffered samples.
+func (c *Chunk) Compact() {
+	c.encode()
+}
+
+// Reset resets the chunk to the encoded samples in stream. The decoded
+// samples are dropped rather than reused, since iterators may still hold them.
+func (c *Chunk) Reset(stream []byte) {
+	c.data = stream
+	c.ts = nil
+	c.vs = nil
+	c.loaded = len(stream) == 0
+	c.dirty = false
+	c.err = nil
+}
+
+// Iterator returns an iterator over the samples in the chunk, reusing it if
+// it is an iterator of this package. The iterator does not see samples
+// appended after it was created.
+func (c *Chunk) Iterator(it chunkenc.Iterator) chunkenc.Iterator {
+	iter, ok := it.(*Iterator)
+	if !ok {
+		iter = &Iterator{}
+	}
+	if c.loaded {
+		iter.reset(c.ts[:len(c.ts):len(c.ts)], c.vs[:len(c.vs):len(c.vs)], nil)
+		return iter
+	}
+	iter.Reset(c.data)
+	return iter
+}
+
+// load decodes the samples of the chunk into memory.
+func (c *Chunk) load() error {
+	if c.loaded || c.err != nil {
+		return c.err
+	}
+	c.ts, c.vs, c.err = chunk.Decode(c.ts, c.vs, c.data)
+	c.loaded = c.err == nil
+	return c.err
+}
+
+// encode encodes the buffered samples, or an empty chunk if there are no
+// encoded samples yet. A new slice is used since the previous bytes may still
+// be referenced by callers of Bytes.
+func (c *Chunk) encode() {
+	if !c.dirty && c.data != nil {
+		return
+	}
+	data, err := chunk.Encode(nil, c.ts, c.vs)
+	if err != nil {
+		// Appenders never add more than chunk.MaxSamples samples.
+		panic(err)
+	}
+	c.data = data
+	c.dirty = false
+}
+
+type appender struct {
+	c *Chunk
+}
+
+// Append buffers a sample. It panics if the chunk already holds
+// chunk.MaxSamples samples.
+func (a *appender) Append(t int64, v float64) {
+	if len(a.c.ts) >= chunk.MaxSamples {
+		panic(fmt.Sprintf("appended more than %d samples to a chunk", chunk.MaxSamples))
+	}
+	a.c.ts = append(a.c.ts, t)
+	a.c.vs = append(a.c.vs, v)
+	a.c.dirty = true
+}
+
+func (a *appender) AppendHistogram(*chunkenc.HistogramAppender, int64, *histogram.Histogram, bool) (chunkenc.Chunk, bool, chunkenc.Appender, error) {
+	panic("appended a histogram sample to a float chunk")
+}
+
+func (a *appender) AppendFloatHistogram(*chunkenc.FloatHistogramAppender, int64, *histogram.FloatHistogram, bool) (chunkenc.Chunk, bool, chunkenc.Appender, error) {
+	panic("appended a float histogram sample to a float chunk")
+}
+
+// Iterator is a chunkenc.Iterator over the samples of a chunk.
+type Iterator struct {
+	ts  []int64
+	vs  []float64
+	i   int
+	err error
+
+	// Buffers owned by the iterator for decoding encoded chunks.
+	tsBuf []int64
+	vsBuf []float64
+}
+
+// Reset prepares the iterator to iterate over the samples of an encoded chunk.
+func (it *Iterator) Reset(data []byte) {
+	ts, vs, err := chunk.Decode(it.tsBuf, it.vsBuf, data)
+	it.tsBuf, it.vsBuf = ts, vs
+	it.reset(ts, vs, err)
+}
+
+func (it *Iterator) reset(ts []int64, vs []float64, err error) {
+	it.ts, it.vs, it.err = ts, vs, err
+	it.i = -1
+}
+
+// Next advances the iterator to the next sample.
+func (it *Iterator) Next() chunkenc.ValueType {
+	if it.err != nil || it.i+1 >= len(it.ts) {
+		it.i = len(it.ts)
+		return chunkenc.ValNone
+	}
+	it.i++
+	return chunkenc.ValFloat
+}
+
+// Seek advances the iterator to the first sample with a timestamp equal or
+// greater than t. Timestamps are increasing, so the sample is found with a
+// binary search. The signature is the one of chunkenc.Iterator rather than
+// io.Seeker, so the stdmethods check of go vet is disabled for this module.
+func (it *Iterator) Seek(t int64) chunkenc.ValueType {
+	if it.err != nil || it.i >= len(it.ts) {
+		return chunkenc.ValNone
+	}
+	from := max(it.i, 0)
+	it.i = from + sort.Search(len(it.ts)-from, func(i int) bool {
+		return it.ts[from+i] >= t
+	})
+	if it.i >= len(it.ts) {
+		return chunkenc.ValNone
+	}
+	return chunkenc.ValFloat
+}
+
+// At returns the current sample.
+func (it *Iterator) At() (int64, float64) {
+	return it.ts[it.i], it.vs[it.i]
+}
+
+func (it *Iterator) AtHistogram(*histogram.Histogram) (int64, *histogram.Histogram) {
+	panic("cannot call Iterator.AtHistogram")
+}
+
+func (it *Iterator) AtFloatHistogram(*histogram.FloatHistogram) (int64, *histogram.FloatHistogram) {
+	panic("cannot call Iterator.AtFloatHistogram")
+}
+
+// AtT returns the timestamp of the current sample.
+func (it *Iterator) AtT() int64 {
+	return it.ts[it.i]
+}
+
+// Err returns the error found while decoding the chunk, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+var (
+	_ chunkenc.Chunk    = (*Chunk)(nil)
+	_ chunkenc.Iterator = (*Iterator)(nil)
+)
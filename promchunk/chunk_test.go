@@ -0,0 +1,171 @@
+package promchunk
+
+import (
+	"math/rand/v2"
+	"testing"
+
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/stretchr/testify/require"
+)
+
+type sample struct {
+	t int64
+	v float64
+}
+
+func generateSamples(n int) []sample {
+	samples := make([]sample, n)
+	t := int64(1700000000000)
+	for i := range samples {
+		t += 15_000 + rand.Int64N(100)
+		samples[i] = sample{t: t, v: float64(rand.IntN(100000)) / 100}
+	}
+	return samples
+}
+
+func appendSamples(t *testing.T, c chunkenc.Chunk, samples []sample) {
+	app, err := c.Appender()
+	require.NoError(t, err)
+	for _, s := range samples {
+		app.Append(s.t, s.v)
+	}
+}
+
+func readSamples(t *testing.T, it chunkenc.Iterator) []sample {
+	var samples []sample
+	for it.Next() == chunkenc.ValFloat {
+		ts, v := it.At()
+		require.Equal(t, ts, it.AtT())
+		samples = append(samples, sample{t: ts, v: v})
+	}
+	require.NoError(t, it.Err())
+	return samples
+}
+
+func TestChunk(t *testing.T) {
+	samples := generateSamples(120)
+
+	c := NewChunk()
+	require.Equal(t, EncALPDoD, c.Encoding())
+	appendSamples(t, c, samples[:60])
+	require.Equal(t, 60, c.NumSamples())
+	require.Equal(t, samples[:60], readSamples(t, c.Iterator(nil)))
+
+	// Iterators do not see samples appended after they were created.
+	it := c.Iterator(nil)
+	appendSamples(t, c, samples[60:])
+	require.Equal(t, samples[:60], readSamples(t, it))
+	require.Equal(t, samples, readSamples(t, c.Iterator(it)))
+
+	// Reload the chunk from its bytes and continue appending.
+	c.Compact()
+	reloaded := NewChunk()
+	reloaded.Reset(c.Bytes())
+	require.Equal(t, 120, reloaded.NumSamples())
+	require.Equal(t, samples, readSamples(t, reloaded.Iterator(nil)))
+
+	extra := sample{t: samples[119].t + 15_000, v: 42}
+	appendSamples(t, reloaded, []sample{extra})
+	require.Equal(t, append(samples, extra), readSamples(t, reloaded.Iterator(nil)))
+	require.Len(t, readSamples(t, c.Iterator(nil)), 120)
+}
+
+func TestResetWithOpenIterator(t *testing.T) {
+	samples, other := generateSamples(120), generateSamples(120)
+	otherChunk := NewChunk()
+	appendSamples(t, otherChunk, other)
+
+	// Chunks are reused through pools while iterators may still be open.
+	c := NewChunk()
+	appendSamples(t, c, samples)
+	c.Compact()
+	c.Reset(c.Bytes())
+	require.Equal(t, 120, c.NumSamples())
+	it := c.Iterator(nil)
+	c.Reset(otherChunk.Bytes())
+	require.Equal(t, 120, c.NumSamples())
+	require.Equal(t, samples, readSamples(t, it))
+	require.Equal(t, other, readSamples(t, c.Iterator(nil)))
+}
+
+func TestEmptyChunkBytes(t *testing.T) {
+	c := NewChunk()
+	require.NotNil(t, c.Bytes())
+
+	fromBytes := NewChunk()
+	fromBytes.Reset(c.Bytes())
+	require.Equal(t, 0, fromBytes.NumSamples())
+	require.Empty(t, readSamples(t, fromBytes.Iterator(nil)))
+}
+
+func TestChunkMatchesXOR(t *testing.T) {
+	samples := generateSamples(120)
+
+	xor := chunkenc.NewXORChunk()
+	appendSamples(t, xor, samples)
+	c := NewChunk()
+	appendSamples(t, c, samples)
+
+	fromBytes := NewChunk()
+	fromBytes.Reset(c.Bytes())
+	require.Equal(t, readSamples(t, xor.Iterator(nil)), readSamples(t, fromBytes.Iterator(nil)))
+	require.Less(t, len(c.Bytes()), len(xor.Bytes()))
+}
+
+func TestSeek(t *testing.T) {
+	samples := generateSamples(120)
+	c := NewChunk()
+	appendSamples(t, c, samples)
+
+	xor := chunkenc.NewXORChunk()
+	appendSamples(t, xor, samples)
+
+	seeks := []int64{
+		samples[0].t - 1,
+		samples[10].t,
+		samples[10].t, // Seeking to the current sample does not advance.
+		samples[5].t,  // Seeking backwards does not move the iterator.
+		samples[50].t + 1,
+		samples[119].t,
+		samples[119].t + 1,
+		samples[119].t + 2,
+	}
+	var (
+		it    = c.Iterator(nil)
+		xorIt = xor.Iterator(nil)
+	)
+	for _, ts := range seeks {
+		want := xorIt.Seek(ts)
+		require.Equal(t, want, it.Seek(ts), "seek to %d", ts)
+		if want == chunkenc.ValFloat {
+			wantT, wantV := xorIt.At()
+			gotT, gotV := it.At()
+			require.Equal(t, wantT, gotT)
+			require.Equal(t, wantV, gotV)
+		}
+	}
+	require.Equal(t, chunkenc.ValNone, it.Next())
+
+	// Seek on a fresh iterator, then continue with Next.
+	it = c.Iterator(it)
+	require.Equal(t, chunkenc.ValFloat, it.Seek(samples[30].t-1))
+	require.Equal(t, samples[30].t, it.AtT())
+	require.Equal(t, chunkenc.ValFloat, it.Next())
+	require.Equal(t, samples[31].t, it.AtT())
+}
+
+func TestCorruptChunk(t *testing.T) {
+	c := NewChunk()
+	appendSamples(t, c, generateSamples(10))
+	data := c.Bytes()
+
+	corrupt := NewChunk()
+	corrupt.Reset(data[:len(data)-1])
+	require.Equal(t, 0, corrupt.NumSamples())
+	_, err := corrupt.Appender()
+	require.Error(t, err)
+
+	it := corrupt.Iterator(nil)
+	require.Equal(t, chunkenc.ValNone, it.Next())
+	require.Error(t, it.Err())
+}
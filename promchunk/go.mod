@@ -0,0 +1,19 @@
+module github.com/fpetkovski/tscodec-go/promchunk
+
+go 1.25.1
+
+require (
+	github.com/fpetkovski/tscodec-go v0.0.0-00010101000000-000000000000
+	github.com/prometheus/prometheus v0.307.3
+	github.com/stretchr/testify v1.11.1
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect
+	github.com/parquet-go/bitpack v0.1.1-0.20251029180122-fa1aca9bf2d1 // indirect
+	github.com/pmezard/go-difflib v1.0.1-0.20181226105442-5d4384ee4fb2 // indirect
+	golang.org/x/sys v0.37.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/fpetkovski/tscodec-go => ../